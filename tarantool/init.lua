@@ -4,6 +4,9 @@ box.cfg{
 
 box.schema.user.grant('guest', 'read,write,execute', 'universe')
 
+local fiber = require('fiber')
+local uuid = require('uuid')
+
 
 s = box.schema.space.create('polls', {if_not_exists = true})
 
@@ -12,11 +15,113 @@ s:format({
       {name = 'owner_id', type = 'string'},
       {name = 'question', type = 'string'},
       {name = 'options', type = 'array'},
-      {name = 'is_active', type = 'boolean'}
+      {name = 'is_active', type = 'boolean'},
+      {name = 'locked_votes', type = 'boolean', is_nullable = true},
+      {name = 'results_visibility', type = 'string', is_nullable = true},
+      {name = 'channel_id', type = 'string', is_nullable = true},
+      {name = 'quorum', type = 'unsigned', is_nullable = true},
+      {name = 'threshold', type = 'unsigned', is_nullable = true},
+      {name = 'voters_mode', type = 'string', is_nullable = true},
+      {name = 'voters', type = 'array', is_nullable = true},
+      {name = 'closes_at', type = 'integer', is_nullable = true},
+      {name = 'remind_before', type = 'integer', is_nullable = true},
+      {name = 'reminded', type = 'boolean', is_nullable = true},
+      {name = 'anonymous', type = 'boolean', is_nullable = true},
+      {name = 'deleted_at', type = 'integer', is_nullable = true},
+      {name = 'closed_at', type = 'integer', is_nullable = true},
+      {name = 'votes_anonymized', type = 'boolean', is_nullable = true},
+      {name = 'number', type = 'unsigned', is_nullable = true},
+      {name = 'post_id', type = 'string', is_nullable = true},
+      {name = 'reactions', type = 'string', is_nullable = true},
+      {name = 'open_options', type = 'string', is_nullable = true},
+      {name = 'reminded_at', type = 'integer', is_nullable = true}
 })
 
 s:create_index('primary', {parts = {'id'}, if_not_exists = true})
-s:create_index('owner', {parts = {'owner_id'}, if_not_exists = true})
+s:create_index('owner', {parts = {'owner_id'}, unique = false, if_not_exists = true})
+-- the index used to be unique, which allowed a single poll per owner
+s.index.owner:alter({unique = false})
+s:create_index('channel', {
+    parts = {{'channel_id', is_nullable = true}},
+    unique = false,
+    if_not_exists = true
+})
+s:create_index('deleted', {
+    parts = {{'deleted_at', is_nullable = true}},
+    unique = false,
+    if_not_exists = true
+})
+s:create_index('closed', {
+    parts = {{'closed_at', is_nullable = true}},
+    unique = false,
+    if_not_exists = true
+})
+-- the retention policy looks up the closed polls whose votes still carry
+-- the user ids
+s:create_index('anonymized', {
+    parts = {{'votes_anonymized', is_nullable = true}, {'closed_at', is_nullable = true}},
+    unique = false,
+    if_not_exists = true
+})
+s:create_index('number', {
+    parts = {{'channel_id', is_nullable = true}, {'number', is_nullable = true}},
+    unique = false,
+    if_not_exists = true
+})
+s:create_index('post', {
+    parts = {{'post_id', is_nullable = true}},
+    unique = false,
+    if_not_exists = true
+})
+s:create_index('deadline', {
+    parts = {{'is_active'}, {'closes_at', is_nullable = true}},
+    unique = false,
+    if_not_exists = true
+})
+
+-- polls closed before the closing time was recorded count as closed now
+local closed = {}
+for _, poll in s.index.deadline:pairs({false}) do
+    if poll.closed_at == nil then
+        table.insert(closed, poll.id)
+    end
+end
+for _, id in ipairs(closed) do
+    s:update(id, {{'=', 'closed_at', os.time()}})
+end
+
+-- polls closed before the anonymization was recorded have not been
+-- anonymized, the flag is set so the anonymized index finds them
+local unmarked = {}
+for _, poll in s.index.anonymized:pairs({box.NULL}) do
+    if (poll.closed_at or 0) > 0 then
+        table.insert(unmarked, poll.id)
+    end
+end
+for _, id in ipairs(unmarked) do
+    s:update(id, {{'=', 'votes_anonymized', false}})
+end
+
+cn = box.schema.space.create('channel_counters', {if_not_exists = true})
+cn:format({
+    {name = 'channel_id', type = 'string'},
+    {name = 'last_number', type = 'unsigned'}
+})
+cn:create_index('primary', {parts = {'channel_id'}, if_not_exists = true})
+
+-- next_poll_number returns the next channel-local number of a poll, the
+-- numbers are never reused, even after the polls are deleted.
+function next_poll_number(channel_id)
+    return box.atomic(function()
+        local counter = box.space.channel_counters:get(channel_id)
+        local number = 1
+        if counter ~= nil then
+            number = counter.last_number + 1
+        end
+        box.space.channel_counters:replace({channel_id, number})
+        return number
+    end)
+end
 
 v = box.schema.space.create('votes', {if_not_exists = true})
 v:format({
@@ -27,3 +132,500 @@ v:format({
 v:create_index('primary', {
     parts = {'poll_id', 'user_id'}, if_not_exists = true
 })
+v:create_index('user', {parts = {'user_id'}, unique = false, if_not_exists = true})
+
+sg = box.schema.space.create('suggestions', {if_not_exists = true})
+sg:format({
+    {name = 'id', type = 'string'},
+    {name = 'poll_id', type = 'string'},
+    {name = 'user_id', type = 'string'},
+    {name = 'option', type = 'string'},
+    {name = 'created_at', type = 'integer'}
+})
+sg:create_index('primary', {parts = {'id'}, if_not_exists = true})
+sg:create_index('poll', {parts = {'poll_id'}, unique = false, if_not_exists = true})
+sg:create_index('user', {parts = {'user_id'}, unique = false, if_not_exists = true})
+
+pt = box.schema.space.create('poll_tallies', {if_not_exists = true})
+pt:format({
+    {name = 'poll_id', type = 'string'},
+    {name = 'option', type = 'unsigned'},
+    {name = 'count', type = 'unsigned'}
+})
+pt:create_index('primary', {parts = {'poll_id', 'option'}, if_not_exists = true})
+
+-- add_tally changes the vote counter of the option, it must be called in
+-- the transaction that changes the votes.
+local function add_tally(poll_id, option, delta)
+    local tally = box.space.poll_tallies:get({poll_id, option})
+    local count = delta
+    if tally ~= nil then
+        count = tally.count + delta
+    end
+    if count < 0 then
+        count = 0
+    end
+    box.space.poll_tallies:replace({poll_id, option, count})
+end
+
+-- cast_vote checks the poll and stores the vote of the user in a single
+-- transaction, so the poll cannot be closed or deleted in between. The
+-- choice is 0-based. It returns 'new' or 'changed', or why the vote was
+-- rejected: 'not_found', 'closed', 'invalid_option' or 'locked'.
+function cast_vote(poll_id, user_id, choice)
+    return box.atomic(function()
+        local poll = box.space.polls:get(poll_id)
+        if poll == nil or (poll.deleted_at or 0) > 0 then
+            return 'not_found'
+        end
+        if not poll.is_active then
+            return 'closed'
+        end
+        if choice >= #poll.options then
+            return 'invalid_option'
+        end
+
+        local vote = box.space.votes:get({poll_id, user_id})
+        if vote ~= nil and poll.locked_votes then
+            return 'locked'
+        end
+
+        box.space.votes:replace({poll_id, user_id, choice})
+        if vote == nil then
+            add_tally(poll_id, choice, 1)
+            return 'new'
+        end
+        if vote.choice ~= choice then
+            add_tally(poll_id, vote.choice, -1)
+            add_tally(poll_id, choice, 1)
+        end
+        return 'changed'
+    end)
+end
+
+-- end_poll closes the poll only if it is still active, so of several
+-- concurrent closes exactly one succeeds. It returns 'closed', or
+-- 'not_found' or 'already_closed'.
+function end_poll(poll_id)
+    return box.atomic(function()
+        local poll = box.space.polls:get(poll_id)
+        if poll == nil then
+            return 'not_found'
+        end
+        if not poll.is_active then
+            return 'already_closed'
+        end
+        box.space.polls:update(poll_id, {{'=', 'is_active', false}, {'=', 'closed_at', os.time()}})
+        return 'closed'
+    end)
+end
+
+-- claim_reminder records a reminder requested by the owner of the poll,
+-- unless the previous one was sent less than cooldown seconds ago. It
+-- returns 'claimed', 'too_soon' or 'not_found'.
+function claim_reminder(poll_id, cooldown)
+    return box.atomic(function()
+        local poll = box.space.polls:get(poll_id)
+        if poll == nil then
+            return 'not_found'
+        end
+        local now = os.time()
+        if now - (poll.reminded_at or 0) < cooldown then
+            return 'too_soon'
+        end
+        box.space.polls:update(poll_id, {{'=', 'reminded_at', now}})
+        return 'claimed'
+    end)
+end
+
+-- retract_vote removes the vote of the user from an active poll. It
+-- returns 'retracted', or why the vote was kept: 'not_found', 'closed',
+-- 'locked' or 'no_vote'.
+function retract_vote(poll_id, user_id)
+    return box.atomic(function()
+        local poll = box.space.polls:get(poll_id)
+        if poll == nil or (poll.deleted_at or 0) > 0 then
+            return 'not_found'
+        end
+        if not poll.is_active then
+            return 'closed'
+        end
+        if poll.locked_votes then
+            return 'locked'
+        end
+
+        local vote = box.space.votes:delete({poll_id, user_id})
+        if vote == nil then
+            return 'no_vote'
+        end
+        add_tally(poll_id, vote.choice, -1)
+        return 'retracted'
+    end)
+end
+
+-- rebuild_tallies recomputes the tallies of the poll, or of all polls when
+-- poll_id is nil, from the raw votes. It returns the ids of the polls whose
+-- tallies did not match the votes.
+function rebuild_tallies(poll_id)
+    local ids = {}
+    if poll_id ~= nil then
+        table.insert(ids, poll_id)
+    else
+        for _, poll in box.space.polls:pairs() do
+            table.insert(ids, poll.id)
+        end
+    end
+
+    local fixed = {}
+    for _, id in ipairs(ids) do
+        box.atomic(function()
+            local counts = {}
+            for _, vote in box.space.votes:pairs({id}) do
+                counts[vote.choice] = (counts[vote.choice] or 0) + 1
+            end
+            local stored = {}
+            for _, tally in box.space.poll_tallies:pairs({id}) do
+                stored[tally.option] = tally.count
+            end
+
+            local wrong = false
+            for option, count in pairs(stored) do
+                if (counts[option] or 0) ~= count then
+                    wrong = true
+                    box.space.poll_tallies:delete({id, option})
+                end
+            end
+            for option, count in pairs(counts) do
+                if stored[option] ~= count then
+                    wrong = true
+                    box.space.poll_tallies:replace({id, option, count})
+                end
+            end
+            if wrong then
+                table.insert(fixed, id)
+            end
+        end)
+    end
+    return fixed
+end
+
+-- editable_poll returns the active poll for an edit, or why it cannot be
+-- edited: 'not_found' or 'closed'.
+local function editable_poll(poll_id)
+    local poll = box.space.polls:get(poll_id)
+    if poll == nil or (poll.deleted_at or 0) > 0 then
+        return nil, 'not_found'
+    end
+    if not poll.is_active then
+        return nil, 'closed'
+    end
+    return poll
+end
+
+-- option_key is the form of the option compared for duplicates: lower
+-- case with the spaces collapsed, like sameOption in the service.
+local function option_key(option)
+    local collapsed = option:gsub('%s+', ' ')
+    return utf8.lower(collapsed:match('^ ?(.-) ?$'))
+end
+
+-- has_option tells whether the options already contain the option, the
+-- 0-based option skip is left out of the comparison.
+local function has_option(options, option, skip)
+    local key = option_key(option)
+    for i, existing in ipairs(options) do
+        if i - 1 ~= skip and option_key(existing) == key then
+            return true
+        end
+    end
+    return false
+end
+
+-- append_option appends an option to an active poll that has fewer than
+-- max options and no such option yet, within the caller's transaction. It
+-- returns 'added', 'not_found', 'closed', 'too_many' or 'duplicate'.
+local function append_option(poll_id, option, max)
+    local poll, status = editable_poll(poll_id)
+    if poll == nil then
+        return status
+    end
+    if #poll.options >= max then
+        return 'too_many'
+    end
+    if has_option(poll.options, option) then
+        return 'duplicate'
+    end
+    local options = table.copy(poll.options)
+    table.insert(options, option)
+    box.space.polls:update(poll_id, {{'=', 'options', options}})
+    return 'added'
+end
+
+-- add_option appends an option to an active poll, see append_option.
+function add_option(poll_id, option, max)
+    return box.atomic(append_option, poll_id, option, max)
+end
+
+-- approve_suggestion adds the suggested option to its poll and deletes the
+-- suggestion in one transaction, so that it is applied at most once. It
+-- returns 'no_suggestion' or the status of append_option, the suggestion
+-- is kept unless the option has been added.
+function approve_suggestion(suggestion_id, max)
+    return box.atomic(function()
+        local suggestion = box.space.suggestions:get(suggestion_id)
+        if suggestion == nil then
+            return 'no_suggestion'
+        end
+        local status = append_option(suggestion.poll_id, suggestion.option, max)
+        if status == 'added' then
+            box.space.suggestions:delete(suggestion_id)
+        end
+        return status
+    end)
+end
+
+-- rename_option changes the 0-based option of an active poll unless
+-- another option has the text, the votes for it are kept. It returns
+-- 'renamed', 'not_found', 'closed', 'invalid_option' or 'duplicate'.
+function rename_option(poll_id, option, text)
+    return box.atomic(function()
+        local poll, status = editable_poll(poll_id)
+        if poll == nil then
+            return status
+        end
+        if option >= #poll.options then
+            return 'invalid_option'
+        end
+        if has_option(poll.options, text, option) then
+            return 'duplicate'
+        end
+        local options = table.copy(poll.options)
+        options[option + 1] = text
+        box.space.polls:update(poll_id, {{'=', 'options', options}})
+        return 'renamed'
+    end)
+end
+
+-- remove_option removes the 0-based option of an active poll. An option
+-- with votes is removed only when clear is set, its votes are deleted and
+-- the votes for the following options are shifted with their tallies. It
+-- returns the status, 'removed', 'not_found', 'closed', 'invalid_option',
+-- 'last_option' or 'has_votes', and the users whose votes were deleted.
+function remove_option(poll_id, option, clear)
+    return box.atomic(function()
+        local poll, status = editable_poll(poll_id)
+        if poll == nil then
+            return status, {}
+        end
+        if option >= #poll.options then
+            return 'invalid_option', {}
+        end
+        if #poll.options == 1 then
+            return 'last_option', {}
+        end
+
+        local cleared, shifted = {}, {}
+        for _, vote in box.space.votes:pairs({poll_id}) do
+            if vote.choice == option then
+                table.insert(cleared, vote.user_id)
+            elseif vote.choice > option then
+                table.insert(shifted, vote)
+            end
+        end
+        if #cleared > 0 and not clear then
+            return 'has_votes', {}
+        end
+
+        for _, user_id in ipairs(cleared) do
+            box.space.votes:delete({poll_id, user_id})
+        end
+        for _, vote in ipairs(shifted) do
+            box.space.votes:update({poll_id, vote.user_id}, {{'-', 'choice', 1}})
+        end
+        for i = option, #poll.options - 1 do
+            box.space.poll_tallies:delete({poll_id, i})
+            local next = box.space.poll_tallies:get({poll_id, i + 1})
+            if next ~= nil then
+                box.space.poll_tallies:replace({poll_id, i, next.count})
+            end
+        end
+
+        local options = table.copy(poll.options)
+        table.remove(options, option + 1)
+        box.space.polls:update(poll_id, {{'=', 'options', options}})
+        return 'removed', cleared
+    end)
+end
+
+-- delete_by_poll deletes the tuples of the poll from a space whose primary
+-- key starts with the poll id. It returns the number of deleted tuples.
+local function delete_by_poll(space, poll_id)
+    local keys = {}
+    for _, tuple in space:pairs({poll_id}) do
+        table.insert(keys, {tuple[1], tuple[2]})
+    end
+    for _, key in ipairs(keys) do
+        space:delete(key)
+    end
+    return #keys
+end
+
+-- delete_poll deletes the poll together with its votes, tallies and
+-- suggestions in one transaction. It returns false if there is no such poll.
+function delete_poll(poll_id)
+    return box.atomic(function()
+        if box.space.polls:delete(poll_id) == nil then
+            return false
+        end
+        delete_by_poll(box.space.votes, poll_id)
+        delete_by_poll(box.space.poll_tallies, poll_id)
+        for _, suggestion in ipairs(box.space.suggestions.index.poll:select({poll_id})) do
+            box.space.suggestions:delete(suggestion.id)
+        end
+        return true
+    end)
+end
+
+-- purge_orphans deletes the votes and tallies of polls that no longer
+-- exist, left behind by versions that deleted only the poll itself. It
+-- returns the number of purged votes.
+function purge_orphans()
+    local purged = 0
+    for _, space in ipairs({box.space.votes, box.space.poll_tallies}) do
+        local poll_id = ''
+        while true do
+            local tuple = space.index.primary:select({poll_id}, {iterator = 'GT', limit = 1})[1]
+            if tuple == nil then
+                break
+            end
+            poll_id = tuple[1]
+
+            box.atomic(function()
+                if box.space.polls:get(poll_id) == nil then
+                    local deleted = delete_by_poll(space, poll_id)
+                    if space == box.space.votes then
+                        purged = purged + deleted
+                    end
+                end
+            end)
+            fiber.yield()
+        end
+    end
+    return purged
+end
+
+-- anonymize_votes replaces the user ids in the votes of the poll with
+-- random ones, so the results and tallies stay the same but nobody can
+-- tell who voted. It returns the number of anonymized votes.
+function anonymize_votes(poll_id)
+    return box.atomic(function()
+        local votes = box.space.votes:select({poll_id})
+        for _, vote in ipairs(votes) do
+            box.space.votes:delete({poll_id, vote.user_id})
+            box.space.votes:insert({poll_id, 'anonymous:' .. uuid.str(), vote.choice})
+        end
+        box.space.polls:update(poll_id, {{'=', 'votes_anonymized', true}})
+        return #votes
+    end)
+end
+
+-- the votes cast before the tallies were introduced are counted once
+if box.space.poll_tallies:len() == 0 and box.space.votes:len() > 0 then
+    rebuild_tallies()
+end
+
+o = box.schema.space.create('reminder_optouts', {if_not_exists = true})
+o:format({
+    {name = 'user_id', type = 'string'}
+})
+o:create_index('primary', {parts = {'user_id'}, if_not_exists = true})
+
+-- reminder_opted_out returns the users of the list who have turned the
+-- reminders off, so a page of channel members is checked in one call.
+function reminder_opted_out(user_ids)
+    local opted_out = {}
+    for _, user_id in ipairs(user_ids) do
+        if box.space.reminder_optouts:get(user_id) ~= nil then
+            table.insert(opted_out, user_id)
+        end
+    end
+    return opted_out
+end
+
+t = box.schema.space.create('templates', {if_not_exists = true})
+t:format({
+    {name = 'scope_id', type = 'string'},
+    {name = 'name', type = 'string'},
+    {name = 'scope', type = 'string'},
+    {name = 'owner_id', type = 'string'},
+    {name = 'question', type = 'string'},
+    {name = 'options', type = 'array'},
+    {name = 'flags', type = 'array'}
+})
+t:create_index('primary', {parts = {'scope_id', 'name'}, if_not_exists = true})
+
+sc = box.schema.space.create('schedules', {if_not_exists = true})
+sc:format({
+    {name = 'id', type = 'string'},
+    {name = 'owner_id', type = 'string'},
+    {name = 'channel_id', type = 'string'},
+    {name = 'template_scope_id', type = 'string'},
+    {name = 'template_name', type = 'string'},
+    {name = 'cron', type = 'string'},
+    {name = 'timezone', type = 'string'},
+    {name = 'close_after', type = 'integer'},
+    {name = 'next_run', type = 'integer'},
+    {name = 'paused', type = 'boolean'}
+})
+sc:create_index('primary', {parts = {'id'}, if_not_exists = true})
+sc:create_index('owner', {parts = {'owner_id'}, unique = false, if_not_exists = true})
+sc:create_index('next_run', {parts = {'paused', 'next_run'}, unique = false, if_not_exists = true})
+
+w = box.schema.space.create('webhook_queue', {if_not_exists = true})
+w:format({
+    {name = 'id', type = 'string'},
+    {name = 'type', type = 'string'},
+    {name = 'payload', type = 'string'},
+    {name = 'pending_urls', type = 'array', is_nullable = true},
+    {name = 'attempts', type = 'unsigned'},
+    {name = 'next_attempt', type = 'integer'}
+})
+w:create_index('primary', {parts = {'id'}, if_not_exists = true})
+w:create_index('next_attempt', {parts = {'next_attempt'}, unique = false, if_not_exists = true})
+
+-- erase_user removes the user from the votes, the suggestions, the
+-- reminder opt-outs and the queued webhook events in one transaction. The
+-- votes are either anonymized, so the results stay the same, or deleted
+-- together with their share of the tallies, the events keep 'erased' in
+-- place of the user ID. It returns the numbers of erased votes, deleted
+-- suggestions and redacted events.
+function erase_user(user_id, anonymize)
+    return box.atomic(function()
+        local votes = box.space.votes.index.user:select({user_id})
+        for _, vote in ipairs(votes) do
+            box.space.votes:delete({vote.poll_id, user_id})
+            if anonymize then
+                box.space.votes:insert({vote.poll_id, 'anonymous:' .. uuid.str(), vote.choice})
+            else
+                add_tally(vote.poll_id, vote.choice, -1)
+            end
+        end
+
+        local suggestions = box.space.suggestions.index.user:select({user_id})
+        for _, suggestion in ipairs(suggestions) do
+            box.space.suggestions:delete(suggestion.id)
+        end
+        box.space.reminder_optouts:delete(user_id)
+
+        local quoted = '"' .. user_id:gsub('%p', '%%%0') .. '"'
+        local events = 0
+        for _, event in box.space.webhook_queue:pairs() do
+            local payload, found = event.payload:gsub(quoted, '"erased"')
+            if found > 0 then
+                box.space.webhook_queue:update(event.id, {{'=', 'payload', payload}})
+                events = events + 1
+            end
+        end
+        return #votes, #suggestions, events
+    end)
+end
@@ -2,11 +2,17 @@ package main
 
 import (
 	"log/slog"
+	_ "time/tzdata"
+	"votty/internal/api"
 	"votty/internal/app"
 	"votty/internal/config"
+	"votty/internal/handlers"
 	"votty/internal/logger"
 	"votty/internal/mattermost"
+	"votty/internal/renderer"
+	"votty/internal/service"
 	"votty/internal/storage/tarantool"
+	"votty/internal/webhook"
 )
 
 const version int = 1
@@ -32,7 +38,23 @@ func main() {
 		return
 	}
 
-	if err := app.NewApp(log, storage, bot).Run(); err != nil {
+	webhooks := webhook.New(log, cfg)
+
+	users := renderer.NewUsers(log, bot.APIv4Client)
+	notifier := mattermost.NewNotifier(log, bot, users)
+	polls := service.NewPolls(log, storage, bot.APIv4Client, notifier, bot.UserID, cfg.DeleteGracePeriod, cfg.MaxOptions, len(cfg.WebhookURLs) > 0)
+	templates := service.NewTemplates(log, storage, bot.APIv4Client)
+	schedules := service.NewSchedules(log, storage, bot.APIv4Client, polls, templates)
+	retention := service.NewRetention(log, storage, bot.APIv4Client, cfg.RetentionPeriod, cfg.AnonymizeAfter)
+	privacy := service.NewPrivacy(log, storage, bot.APIv4Client)
+	handler := handlers.New(log, bot.APIv4Client, polls, templates, schedules, retention, privacy, users, cfg.PublicReplies, cfg.BotURL, bot.UserID)
+
+	var restAPI *api.API
+	if len(cfg.APIKeys) > 0 {
+		restAPI = api.New(log, polls, cfg.APIKeys)
+	}
+
+	if err := app.NewApp(log, storage, bot, webhooks, handler, polls, schedules, retention, restAPI, cfg.HTTPAddr).Run(); err != nil {
 		log.Error("failed to start votty-bot.", err)
 	}
 
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"github.com/mattermost/mattermost/server/public/model"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"votty/internal/models"
+	"votty/internal/service"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// userHeader carries the Mattermost user on whose behalf the request is made.
+const userHeader = "X-Votty-User-Id"
+
+// API is the authenticated HTTP JSON API over the poll service.
+type API struct {
+	log   *slog.Logger
+	polls *service.Polls
+	keys  []string
+}
+
+func New(log *slog.Logger, polls *service.Polls, keys []string) *API {
+	return &API{log, polls, keys}
+}
+
+// Register mounts the API routes on the mux.
+func (a *API) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPISpec)
+	})
+
+	mux.Handle("POST /api/v1/polls", a.auth(a.createPoll))
+	mux.Handle("GET /api/v1/polls", a.auth(a.listPolls))
+	mux.Handle("GET /api/v1/polls/{id}", a.auth(a.getPoll))
+	mux.Handle("DELETE /api/v1/polls/{id}", a.auth(a.deletePoll))
+	mux.Handle("POST /api/v1/polls/{id}/close", a.auth(a.closePoll))
+	mux.Handle("GET /api/v1/polls/{id}/results", a.auth(a.results))
+	mux.Handle("POST /api/v1/polls/{id}/votes", a.auth(a.vote))
+	mux.Handle("DELETE /api/v1/polls/{id}/votes", a.auth(a.retractVote))
+}
+
+type createPollRequest struct {
+	ChannelID         string   `json:"channel_id"`
+	Question          string   `json:"question"`
+	Options           []string `json:"options"`
+	LockedVotes       bool     `json:"locked_votes"`
+	Anonymous         bool     `json:"anonymous"`
+	ResultsVisibility string   `json:"results_visibility"`
+	Quorum            uint64   `json:"quorum"`
+	Threshold         uint64   `json:"threshold"`
+	VotersMode        string   `json:"voters_mode"`
+	// Voters are usernames for the users mode or a group name for the
+	// group mode, they are resolved to IDs on creation.
+	Voters       []string   `json:"voters"`
+	Deadline     *time.Time `json:"deadline"`
+	RemindBefore int64      `json:"remind_before"`
+}
+
+type voteRequest struct {
+	Choice int `json:"choice"`
+}
+
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+func (a *API) createPoll(w http.ResponseWriter, r *http.Request, actor service.Actor) {
+	var req createPollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Code: "bad_request", Message: err.Error()})
+		return
+	}
+	if req.ChannelID != "" && !model.IsValidId(req.ChannelID) {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Code: "invalid_field", Field: "channel_id", Message: "invalid channel id"})
+		return
+	}
+
+	poll := &models.Poll{
+		ChannelID:         req.ChannelID,
+		Question:          req.Question,
+		Options:           req.Options,
+		LockedVotes:       req.LockedVotes,
+		Anonymous:         req.Anonymous,
+		ResultsVisibility: req.ResultsVisibility,
+		Quorum:            req.Quorum,
+		Threshold:         req.Threshold,
+		VotersMode:        req.VotersMode,
+		Voters:            req.Voters,
+		RemindBefore:      req.RemindBefore,
+	}
+	if req.Deadline != nil {
+		poll.ClosesAt = req.Deadline.Unix()
+	}
+
+	poll, err := a.polls.Create(r.Context(), actor, poll)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	if poll.ChannelID != "" {
+		a.polls.Announce(r.Context(), poll)
+	}
+	writeJSON(w, http.StatusCreated, poll)
+}
+
+// listPolls returns the polls the actor may see, the vote counts are
+// served only by results, which applies the results visibility.
+func (a *API) listPolls(w http.ResponseWriter, r *http.Request, actor service.Actor) {
+	var (
+		polls []*models.Poll
+		err   error
+	)
+	switch query := r.URL.Query(); {
+	case query.Get("owner_id") != "":
+		polls, err = a.polls.ListByOwner(query.Get("owner_id"))
+	case query.Get("channel_id") != "":
+		polls, err = a.polls.ListByChannel(query.Get("channel_id"))
+	default:
+		writeJSON(w, http.StatusBadRequest, errorResponse{Code: "bad_request", Message: "owner_id or channel_id is required"})
+		return
+	}
+	if err == nil {
+		polls, err = a.polls.Visible(r.Context(), actor, polls)
+	}
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, polls)
+}
+
+func (a *API) getPoll(w http.ResponseWriter, r *http.Request, actor service.Actor) {
+	poll, err := a.polls.View(r.Context(), actor, r.PathValue("id"))
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, poll)
+}
+
+func (a *API) deletePoll(w http.ResponseWriter, r *http.Request, actor service.Actor) {
+	if _, err := a.polls.Delete(actor, r.PathValue("id")); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) closePoll(w http.ResponseWriter, r *http.Request, actor service.Actor) {
+	poll, err := a.polls.Close(actor, r.PathValue("id"))
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, poll)
+}
+
+func (a *API) results(w http.ResponseWriter, r *http.Request, actor service.Actor) {
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+	results, err := a.polls.Results(r.Context(), actor, r.PathValue("id"), force)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (a *API) vote(w http.ResponseWriter, r *http.Request, actor service.Actor) {
+	var req voteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Code: "bad_request", Message: err.Error()})
+		return
+	}
+
+	result, err := a.polls.Vote(r.Context(), actor, r.PathValue("id"), req.Choice)
+	if err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if result.Changed {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, result.Vote)
+}
+
+func (a *API) retractVote(w http.ResponseWriter, r *http.Request, actor service.Actor) {
+	if err := a.polls.Retract(actor, r.PathValue("id")); err != nil {
+		a.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// auth checks the API key from the Authorization header and takes the
+// acting user from the X-Votty-User-Id header.
+func (a *API) auth(next func(http.ResponseWriter, *http.Request, service.Actor)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !a.validKey(key) {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Code: "unauthorized", Message: "invalid API key"})
+			return
+		}
+
+		userID := r.Header.Get(userHeader)
+		if !model.IsValidId(userID) {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Code: "bad_request", Message: userHeader + " header must be a Mattermost user id"})
+			return
+		}
+
+		next(w, r, service.Actor{UserID: userID})
+	})
+}
+
+func (a *API) validKey(key string) bool {
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *API) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *service.ValidationError
+	var hiddenErr *service.ResultsHiddenError
+
+	switch {
+	case errors.As(err, &validationErr):
+		writeJSON(w, http.StatusUnprocessableEntity, errorResponse{Code: "invalid_field", Field: validationErr.Field, Message: validationErr.Message})
+	case errors.As(err, &hiddenErr):
+		writeJSON(w, http.StatusForbidden, errorResponse{Code: "results_hidden", Message: hiddenErr.Error()})
+	case errors.Is(err, service.ErrPollNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Code: "poll_not_found", Message: err.Error()})
+	case errors.Is(err, service.ErrVoteNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Code: "vote_not_found", Message: err.Error()})
+	case errors.Is(err, service.ErrNotOwner):
+		writeJSON(w, http.StatusForbidden, errorResponse{Code: "not_owner", Message: err.Error()})
+	case errors.Is(err, service.ErrNotEligible):
+		writeJSON(w, http.StatusForbidden, errorResponse{Code: "not_eligible", Message: err.Error()})
+	case errors.Is(err, service.ErrPollClosed):
+		writeJSON(w, http.StatusConflict, errorResponse{Code: "poll_closed", Message: err.Error()})
+	case errors.Is(err, service.ErrVoteLocked):
+		writeJSON(w, http.StatusConflict, errorResponse{Code: "vote_locked", Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidOption):
+		writeJSON(w, http.StatusUnprocessableEntity, errorResponse{Code: "invalid_option", Field: "choice", Message: err.Error()})
+	case errors.Is(err, context.Canceled):
+	default:
+		a.log.Error("API request failed",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("error", err.Error()),
+		)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Code: "internal_error", Message: "internal error"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -2,24 +2,64 @@ package app
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"github.com/mattermost/mattermost/server/public/model"
 	"golang.org/x/exp/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
+	"time"
+	"votty/internal/api"
 	"votty/internal/handlers"
 	"votty/internal/mattermost"
+	"votty/internal/service"
 	"votty/internal/storage/tarantool"
+	"votty/internal/webhook"
+)
+
+const (
+	// deadlineCheckInterval is how often polls are checked for passed
+	// deadlines and due reminders.
+	deadlineCheckInterval = time.Minute
+	// scheduleCheckInterval is how often recurring polls are checked for
+	// due runs, it bounds the precision of cron schedules.
+	scheduleCheckInterval = 30 * time.Second
+	// janitorInterval is how often deleted polls past their grace period,
+	// the votes of polls deleted by older versions and the data expired
+	// by the retention policy are purged.
+	janitorInterval = time.Hour
 )
 
 type App struct {
 	log       *slog.Logger
 	tarantool *tarantool.Storage
 	bot       *mattermost.Bot
+	webhooks  *webhook.Sender
+	handler   *handlers.Handler
+	polls     *service.Polls
+	schedules *service.Schedules
+	retention *service.Retention
+	server    *http.Server
 }
 
-func NewApp(log *slog.Logger, tarantool *tarantool.Storage, bot *mattermost.Bot) *App {
-	return &App{log, tarantool, bot}
+// NewApp creates the bot application, the REST API is served only when
+// api is not nil.
+func NewApp(log *slog.Logger, tarantool *tarantool.Storage, bot *mattermost.Bot, webhooks *webhook.Sender, handler *handlers.Handler, polls *service.Polls, schedules *service.Schedules, retention *service.Retention, api *api.API, httpAddr string) *App {
+	mux := http.NewServeMux()
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	handler.Register(mux)
+	if api != nil {
+		api.Register(mux)
+	}
+
+	server := &http.Server{
+		Addr:    httpAddr,
+		Handler: mux,
+	}
+	return &App{log, tarantool, bot, webhooks, handler, polls, schedules, retention, server}
 }
 
 func (a *App) Run() error {
@@ -28,22 +68,53 @@ func (a *App) Run() error {
 	defer a.bot.WebSocketClient.Close()
 	defer a.tarantool.Conn.Close()
 
-	bot, _, err := a.bot.APIv4Client.GetUser(ctx, "me", "")
-	if err != nil {
-		a.log.Error("Failed to retrieve bot data", err)
-		return nil
-	}
+	go func() {
+		if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.log.Error("HTTP server failed", slog.String("error", err.Error()))
+		}
+	}()
+	defer a.server.Shutdown(context.Background())
 
-	botID := bot.Id
+	go a.webhooks.Run(ctx, a.tarantool)
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
+	deadlines := time.NewTicker(deadlineCheckInterval)
+	defer deadlines.Stop()
+
+	schedules := time.NewTicker(scheduleCheckInterval)
+	defer schedules.Stop()
+
+	janitor := time.NewTicker(janitorInterval)
+	defer janitor.Stop()
+
+	// deadline processing sends throttled reminders and may take long, so
+	// it runs beside the event loop and a tick is skipped while the
+	// previous run is still going
+	var processingDeadlines atomic.Bool
+
 	for {
 		select {
+		case <-deadlines.C:
+			if processingDeadlines.CompareAndSwap(false, true) {
+				go func() {
+					defer processingDeadlines.Store(false)
+					a.polls.ProcessDeadlines(ctx)
+				}()
+			}
+		case <-schedules.C:
+			a.schedules.Run(ctx)
+		case <-janitor.C:
+			a.polls.PurgeDeleted()
+			a.polls.PurgeOrphans()
+			a.retention.Run()
 		case event := <-a.bot.WebSocketClient.EventChannel:
-			if event.EventType() == model.WebsocketEventPosted {
-				handlers.PostHandler(ctx, a.tarantool, a.log, a.bot.APIv4Client, event, botID)
+			switch event.EventType() {
+			case model.WebsocketEventPosted:
+				a.handler.Post(ctx, event)
+			case model.WebsocketEventReactionAdded, model.WebsocketEventReactionRemoved:
+				a.handler.Reaction(ctx, event)
 			}
 		case sig := <-quit:
 			a.log.Info("Shutting down...", slog.String("Received signal", sig.String()))
@@ -52,6 +123,4 @@ func (a *App) Run() error {
 		}
 
 	}
-
-	return nil
 }
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// minSweep is the size the cache grows to before the first sweep of the
+// expired entries.
+const minSweep = 1024
+
+// Cache is an in-memory key-value store whose entries expire after a TTL.
+// It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items map[K]item[V]
+	// sweepAt is the size at which Set drops the expired entries.
+	sweepAt int
+}
+
+type item[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func New[K comparable, V any](ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:     ttl,
+		items:   make(map[K]item[V]),
+		sweepAt: minSweep,
+	}
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if time.Now().After(it.expiresAt) {
+		delete(c.items, key)
+		var zero V
+		return zero, false
+	}
+	return it.value, true
+}
+
+// Set stores the value for the TTL. Get drops only the expired entries it
+// reads, so Set sweeps the rest whenever the cache doubles in size.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.items[key] = item[V]{value, now.Add(c.ttl)}
+	if len(c.items) < c.sweepAt {
+		return
+	}
+	for k, it := range c.items {
+		if now.After(it.expiresAt) {
+			delete(c.items, k)
+		}
+	}
+	c.sweepAt = max(2*len(c.items), minSweep)
+}
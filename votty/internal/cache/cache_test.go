@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetExpired(t *testing.T) {
+	c := New[string, bool](time.Hour)
+	c.Set("fresh", true)
+	c.items["stale"] = item[bool]{true, time.Now().Add(-time.Second)}
+
+	if ok, found := c.Get("fresh"); !ok || !found {
+		t.Errorf("Get(fresh) = %v, %v, want true, true", ok, found)
+	}
+	if _, found := c.Get("stale"); found {
+		t.Errorf("Get(stale) found an expired entry")
+	}
+	if _, ok := c.items["stale"]; ok {
+		t.Errorf("Get(stale) kept the expired entry")
+	}
+}
+
+func TestSetSweepsExpired(t *testing.T) {
+	c := New[int, bool](time.Hour)
+	for i := 0; i < minSweep-1; i++ {
+		c.items[i] = item[bool]{true, time.Now().Add(-time.Second)}
+	}
+
+	c.Set(minSweep, true)
+	if len(c.items) != 1 {
+		t.Errorf("len = %d after the sweep, want 1", len(c.items))
+	}
+	if c.sweepAt != minSweep {
+		t.Errorf("sweepAt = %d, want %d", c.sweepAt, minSweep)
+	}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -12,6 +15,26 @@ type Config struct {
 	TarantoolHost     string
 	TarantoolUser     string
 	TarantoolPassword string
+	HTTPAddr          string
+	WebhookURLs       []string
+	WebhookSecret     string
+	APIKeys           []string
+	// DeleteGracePeriod is how long deleted polls stay in the trash.
+	DeleteGracePeriod time.Duration
+	// RetentionPeriod is how long closed polls are kept, 0 keeps them forever.
+	RetentionPeriod time.Duration
+	// AnonymizeAfter is when the votes of closed polls lose their user IDs,
+	// 0 keeps them.
+	AnonymizeAfter time.Duration
+	// PublicReplies are the kinds of bot replies posted to the channel, the
+	// other replies are ephemeral.
+	PublicReplies []string
+	// BotURL is the address Mattermost reaches the bot at for the callbacks
+	// of interactive messages and dialogs.
+	BotURL string
+	// MaxOptions limits the options added to a poll after its creation by
+	// /edit and /suggest.
+	MaxOptions int
 }
 
 func MustLoad() *Config {
@@ -40,11 +63,82 @@ func MustLoad() *Config {
 
 	tarantoolPassword := os.Getenv("TARANTOOL_PASSWORD")
 
+	httpAddr := os.Getenv("HTTP_ADDR")
+	if httpAddr == "" {
+		httpAddr = ":8080"
+	}
+
+	webhookURLs := splitList(os.Getenv("WEBHOOK_URLS"))
+
+	webhookSecret := os.Getenv("WEBHOOK_SECRET")
+	if len(webhookURLs) > 0 && webhookSecret == "" {
+		log.Fatal("Failed to find the WEBHOOK_SECRET environment variable required by WEBHOOK_URLS.")
+	}
+
+	apiKeys := splitList(os.Getenv("API_KEYS"))
+
+	deleteGracePeriod := days("DELETE_GRACE_DAYS", 7)
+	retentionPeriod := days("RETENTION_DAYS", 0)
+	anonymizeAfter := days("ANONYMIZE_AFTER_DAYS", 0)
+
+	publicReplies := splitList(os.Getenv("PUBLIC_REPLIES"))
+	if len(publicReplies) == 0 {
+		publicReplies = []string{"announcement", "outcome"}
+	}
+
+	botURL := strings.TrimSuffix(os.Getenv("BOT_URL"), "/")
+	if botURL == "" {
+		botURL = "http://mattermost-bot:8080"
+	}
+
+	maxOptions := 20
+	if value := os.Getenv("MAX_OPTIONS"); value != "" {
+		var err error
+		maxOptions, err = strconv.Atoi(value)
+		if err != nil || maxOptions < 1 {
+			log.Fatal("MAX_OPTIONS must be a positive number.")
+		}
+	}
+
 	return &Config{
 		env,
 		mattermostURL,
 		botToken,
 		tarantoolHost,
 		tarantoolUser,
-		tarantoolPassword}
+		tarantoolPassword,
+		httpAddr,
+		webhookURLs,
+		webhookSecret,
+		apiKeys,
+		deleteGracePeriod,
+		retentionPeriod,
+		anonymizeAfter,
+		publicReplies,
+		botURL,
+		maxOptions}
+}
+
+// days parses an environment variable holding a number of days.
+func days(name string, defaultDays int) time.Duration {
+	n := defaultDays
+	if value := os.Getenv(name); value != "" {
+		var err error
+		n, err = strconv.Atoi(value)
+		if err != nil || n < 0 {
+			log.Fatalf("%s must be a non-negative number of days.", name)
+		}
+	}
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// splitList parses a comma separated environment variable.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "", want: nil},
+		{value: " , ,", want: nil},
+		{value: "https://a.example", want: []string{"https://a.example"}},
+		{value: " https://a.example , https://b.example,", want: []string{"https://a.example", "https://b.example"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitList(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitList(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDays(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 30 * 24 * time.Hour},
+		{value: "0", want: 0},
+		{value: "7", want: 7 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("VOTTY_TEST_DAYS", tt.value)
+		if got := days("VOTTY_TEST_DAYS", 30); got != tt.want {
+			t.Errorf("days(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"strconv"
+	"strings"
+	"votty/internal/models"
+	"votty/internal/renderer"
+	"votty/internal/service"
+)
+
+func (h *Handler) create(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 3 {
+		return h.usage(renderer.CommandCreate, post)
+	}
+
+	poll := &models.Poll{}
+	question, err := service.ParsePollFlags(poll, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandCreate, post, err)
+	}
+	if question == "" {
+		return h.usage(renderer.CommandCreate, post)
+	}
+	poll.Question = question
+	poll.Options = strings.Split(parts[2], "|")
+
+	poll, err = h.polls.Create(ctx, actor, poll)
+	if err != nil {
+		return h.fail(renderer.CommandCreate, post, err)
+	}
+	return renderer.PollCreated(poll)
+}
+
+// quick creates a poll voted on with reactions. The poll post itself is
+// the reply, it is posted by the service to add the reactions to it.
+func (h *Handler) quick(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 3 {
+		return h.usage(renderer.CommandQuick, post)
+	}
+
+	poll := &models.Poll{Reactions: models.ReactionsAny}
+	question, err := service.ParsePollFlags(poll, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandQuick, post, err)
+	}
+	if question == "" {
+		return h.usage(renderer.CommandQuick, post)
+	}
+	poll.Question = question
+	for _, emoji := range strings.Fields(parts[2]) {
+		poll.Options = append(poll.Options, service.EmojiName(emoji))
+	}
+
+	poll, err = h.polls.Create(ctx, actor, poll)
+	if err != nil {
+		return h.fail(renderer.CommandQuick, post, err)
+	}
+	h.polls.Announce(ctx, poll)
+	return nil
+}
+
+func (h *Handler) createFromTemplate(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 2 {
+		return h.usage(renderer.CommandCreateTemplate, post)
+	}
+
+	t, err := h.templates.Find(ctx, actor, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandCreateTemplate, post, err)
+	}
+
+	poll, err := h.polls.CreateFromTemplate(ctx, actor, t)
+	if err != nil {
+		return h.fail(renderer.CommandCreateTemplate, post, err)
+	}
+	return renderer.PollCreated(poll)
+}
+
+func (h *Handler) vote(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 3 {
+		return h.usage(renderer.CommandVote, post)
+	}
+
+	choice, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return h.fail(renderer.CommandVote, post, service.ErrInvalidOption)
+	}
+	pollID, err := h.polls.Resolve(actor, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandVote, post, err)
+	}
+
+	result, err := h.polls.Vote(ctx, actor, pollID, choice)
+	if err != nil {
+		return h.fail(renderer.CommandVote, post, err)
+	}
+	return renderer.VoteAccepted(result)
+}
+
+func (h *Handler) end(actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 2 {
+		return h.usage(renderer.CommandEnd, post)
+	}
+
+	pollID, err := h.polls.Resolve(actor, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandEnd, post, err)
+	}
+
+	poll, err := h.polls.Close(actor, pollID)
+	if err != nil {
+		return h.fail(renderer.CommandEnd, post, err)
+	}
+	return renderer.PollClosed(poll)
+}
+
+func (h *Handler) delete(actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 2 {
+		return h.usage(renderer.CommandDelete, post)
+	}
+
+	pollID, err := h.polls.Resolve(actor, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandDelete, post, err)
+	}
+
+	trashed, err := h.polls.Delete(actor, pollID)
+	if err != nil {
+		return h.fail(renderer.CommandDelete, post, err)
+	}
+	return renderer.PollDeleted(trashed)
+}
+
+func (h *Handler) undelete(actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 2 {
+		return h.usage(renderer.CommandUndelete, post)
+	}
+
+	pollID, err := h.polls.Resolve(actor, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandUndelete, post, err)
+	}
+
+	poll, err := h.polls.Undelete(actor, pollID)
+	if err != nil {
+		return h.fail(renderer.CommandUndelete, post, err)
+	}
+	return renderer.PollRestored(poll)
+}
+
+func (h *Handler) trash(actor service.Actor, post *model.Post) *model.Post {
+	trash, err := h.polls.Trash(actor)
+	if err != nil {
+		return h.fail("/trash", post, err)
+	}
+	return renderer.Trash(trash)
+}
+
+func (h *Handler) results(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 2 {
+		return h.usage(renderer.CommandResults, post)
+	}
+
+	ref, force := parts[1], len(parts) > 2 && parts[2] == "--force"
+	if ref == "--force" {
+		// the regexp takes the flag for the poll when the poll is omitted
+		ref, force = "", true
+	}
+	pollID, err := h.polls.Resolve(actor, ref)
+	if err != nil {
+		return h.fail(renderer.CommandResults, post, err)
+	}
+
+	results, err := h.polls.Results(ctx, actor, pollID, force)
+	if err != nil {
+		return h.fail(renderer.CommandResults, post, err)
+	}
+	return renderer.Results(results, h.users.Names(ctx, results.Poll.OwnerID))
+}
+
+func (h *Handler) recount(actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 2 {
+		return h.usage(renderer.CommandRecount, post)
+	}
+
+	pollID, err := h.polls.Resolve(actor, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandRecount, post, err)
+	}
+
+	fixed, err := h.polls.Recount(actor, pollID)
+	if err != nil {
+		return h.fail(renderer.CommandRecount, post, err)
+	}
+	return renderer.Recounted(pollID, fixed)
+}
+
+func (h *Handler) remind(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 2 {
+		return h.usage(renderer.CommandRemind, post)
+	}
+
+	pollID, err := h.polls.Resolve(actor, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandRemind, post, err)
+	}
+
+	recipients, err := h.polls.Remind(ctx, actor, pollID)
+	if err != nil {
+		return h.fail(renderer.CommandRemind, post, err)
+	}
+	return renderer.RemindersRequested(recipients)
+}
+
+func (h *Handler) edit(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 4 {
+		return h.usage(renderer.CommandEdit, post)
+	}
+
+	pollID, err := h.polls.Resolve(actor, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandEdit, post, err)
+	}
+
+	var poll *models.Poll
+	switch parts[2] {
+	case "question":
+		poll, err = h.polls.EditQuestion(ctx, actor, pollID, parts[3])
+	case "add":
+		poll, err = h.polls.AddOption(ctx, actor, pollID, parts[3])
+	case "rename":
+		args := editRenameRegex.FindStringSubmatch(parts[3])
+		if args == nil {
+			return h.usage(renderer.CommandEdit, post)
+		}
+		option, _ := strconv.Atoi(args[1])
+		poll, err = h.polls.RenameOption(ctx, actor, pollID, option, args[2])
+	case "remove":
+		args := editRemoveRegex.FindStringSubmatch(parts[3])
+		if args == nil {
+			return h.usage(renderer.CommandEdit, post)
+		}
+		option, _ := strconv.Atoi(args[1])
+		removed, err := h.polls.RemoveOption(ctx, actor, pollID, option, args[2] != "")
+		if err != nil {
+			return h.fail(renderer.CommandEdit, post, err)
+		}
+		return renderer.OptionRemoved(removed)
+	}
+	if err != nil {
+		return h.fail(renderer.CommandEdit, post, err)
+	}
+	return renderer.PollEdited(poll)
+}
+
+func (h *Handler) suggest(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 3 {
+		return h.usage(renderer.CommandSuggest, post)
+	}
+
+	pollID, err := h.polls.Resolve(actor, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandSuggest, post, err)
+	}
+
+	suggested, err := h.polls.Suggest(ctx, actor, pollID, parts[2])
+	if err != nil {
+		return h.fail(renderer.CommandSuggest, post, err)
+	}
+	return renderer.Suggested(suggested)
+}
+
+func (h *Handler) decideSuggestion(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 3 {
+		return h.usage(renderer.CommandSuggestion, post)
+	}
+
+	approve := parts[1] == "approve"
+	decide := h.polls.Reject
+	if approve {
+		decide = h.polls.Approve
+	}
+	suggested, err := decide(ctx, actor, parts[2])
+	if err != nil {
+		return h.fail(renderer.CommandSuggestion, post, err)
+	}
+	return renderer.SuggestionDecided(suggested, approve)
+}
+
+func (h *Handler) reminders(actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 2 {
+		return h.usage(renderer.CommandReminders, post)
+	}
+
+	enabled := parts[1] == "on"
+	if err := h.polls.SetReminders(actor, enabled); err != nil {
+		return h.fail(renderer.CommandReminders, post, err)
+	}
+	return renderer.RemindersSwitched(enabled)
+}
+
+func (h *Handler) saveTemplate(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 4 {
+		return h.usage(renderer.CommandTemplateSave, post)
+	}
+
+	t := &models.Template{
+		Scope:    models.TemplateScopeUser,
+		Question: parts[2],
+		Options:  strings.Split(parts[3], "|"),
+	}
+
+	var name []string
+	for _, token := range strings.Fields(parts[1]) {
+		switch {
+		case token == "--team":
+			t.Scope = models.TemplateScopeTeam
+		case strings.HasPrefix(token, "--") && len(name) == 0:
+			t.Flags = append(t.Flags, token)
+		default:
+			name = append(name, token)
+		}
+	}
+	t.Name = strings.Join(name, " ")
+
+	if err := h.templates.Save(ctx, actor, t); err != nil {
+		return h.fail(renderer.CommandTemplateSave, post, err)
+	}
+	return renderer.TemplateSaved(t)
+}
+
+func (h *Handler) listTemplates(ctx context.Context, actor service.Actor, post *model.Post) *model.Post {
+	own, team, err := h.templates.List(ctx, actor)
+	if err != nil {
+		return h.fail("/template list", post, err)
+	}
+	return renderer.Templates(own, team)
+}
+
+func (h *Handler) deleteTemplate(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 3 {
+		return h.usage(renderer.CommandTemplateDelete, post)
+	}
+
+	if err := h.templates.Delete(ctx, actor, parts[2], parts[1] != ""); err != nil {
+		return h.fail(renderer.CommandTemplateDelete, post, err)
+	}
+	return renderer.TemplateDeleted(parts[2])
+}
+
+func (h *Handler) addSchedule(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 3 {
+		return h.usage(renderer.CommandScheduleAdd, post)
+	}
+
+	sc := &models.Schedule{Cron: parts[2]}
+
+	var channelName, templateName string
+	for _, token := range strings.Fields(parts[1]) {
+		name, value, _ := strings.Cut(strings.TrimPrefix(token, "--"), "=")
+		switch {
+		case !strings.HasPrefix(token, "--") && templateName == "":
+			templateName = token
+		case name == "tz":
+			sc.Timezone = value
+		case name == "channel":
+			channelName = value
+		case name == "close":
+			d, err := service.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return h.fail(renderer.CommandScheduleAdd, post, &service.ValidationError{
+					Field:   "close",
+					Message: "флаг --close принимает длительность, например ```--close=24h```",
+				})
+			}
+			sc.CloseAfter = int64(d.Seconds())
+		default:
+			return h.fail(renderer.CommandScheduleAdd, post, &service.ValidationError{
+				Field:   name,
+				Message: fmt.Sprintf("неизвестный аргумент %s", token),
+			})
+		}
+	}
+
+	sc, err := h.schedules.Add(ctx, actor, sc, templateName, channelName)
+	if err != nil {
+		return h.fail(renderer.CommandScheduleAdd, post, err)
+	}
+	return renderer.ScheduleCreated(sc)
+}
+
+func (h *Handler) listSchedules(actor service.Actor, post *model.Post) *model.Post {
+	schedules, err := h.schedules.List(actor)
+	if err != nil {
+		return h.fail("/schedule list", post, err)
+	}
+	return renderer.Schedules(schedules)
+}
+
+// changeSchedule pauses, resumes or deletes the schedule of its owner.
+func (h *Handler) changeSchedule(actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 3 {
+		return h.usage(renderer.CommandSchedule, post)
+	}
+
+	action, scheduleID := parts[1], parts[2]
+
+	var (
+		sc  *models.Schedule
+		err error
+	)
+	switch action {
+	case "pause":
+		if sc, err = h.schedules.Pause(actor, scheduleID); err == nil {
+			return renderer.SchedulePaused(sc)
+		}
+	case "resume":
+		if sc, err = h.schedules.Resume(actor, scheduleID); err == nil {
+			return renderer.ScheduleResumed(sc)
+		}
+	case "delete":
+		if err = h.schedules.Delete(actor, scheduleID); err == nil {
+			return renderer.ScheduleDeleted(scheduleID)
+		}
+	}
+	return h.fail(renderer.CommandSchedule, post, err)
+}
+
+func (h *Handler) retentionReport(ctx context.Context, actor service.Actor, post *model.Post) *model.Post {
+	report, err := h.retention.Report(ctx, actor)
+	if err != nil {
+		return h.fail(renderer.CommandRetention, post, err)
+	}
+	return renderer.RetentionReport(report)
+}
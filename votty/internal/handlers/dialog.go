@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/mattermost/mattermost/server/public/model"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"votty/internal/models"
+	"votty/internal/renderer"
+	"votty/internal/service"
+)
+
+// dialogPath is where the callbacks of the poll dialog are served.
+const dialogPath = "/mattermost/dialog"
+
+// dialogTTL is how long the button and the dialog it opens stay valid.
+const dialogTTL = time.Hour
+
+// dialogFields maps the fields of the service validation errors to the
+// dialog elements, the flag errors already name the elements.
+var dialogFields = map[string]string{
+	"results_visibility": "results",
+	"voters_mode":        "voters",
+	"channel_id":         "voters",
+	"closes_at":          "deadline",
+	"open_options":       "open",
+	"remind_before":      "remind",
+}
+
+// Register mounts the callbacks of the poll dialog. Mattermost calls them
+// on behalf of the user, who is identified by the signed dialog state.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST "+dialogPath+"/open", h.openDialog)
+	mux.HandleFunc("POST "+dialogPath+"/submit", h.submitDialog)
+}
+
+// createDialog replies to a bare /create with a button opening the poll
+// dialog, dialogs can only be opened in response to an interaction.
+func (h *Handler) createDialog(actor service.Actor) *model.Post {
+	return renderer.CreateButton(h.dialogURL+"/open", h.signState(actor))
+}
+
+func (h *Handler) openDialog(w http.ResponseWriter, r *http.Request) {
+	var req model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	state, _ := req.Context["state"].(string)
+	if _, ok := h.verifyState(state, req.UserId); !ok {
+		writeJSON(w, model.PostActionIntegrationResponse{EphemeralText: renderer.DialogExpired})
+		return
+	}
+
+	_, err := h.client.OpenInteractiveDialog(r.Context(), model.OpenDialogRequest{
+		TriggerId: req.TriggerId,
+		URL:       h.dialogURL + "/submit",
+		Dialog:    renderer.CreateDialog(state),
+	})
+	if err != nil {
+		h.log.Error("Failed to open the poll dialog",
+			slog.String("user_id", req.UserId),
+			slog.String("error", err.Error()),
+		)
+		writeJSON(w, model.PostActionIntegrationResponse{EphemeralText: renderer.Error(renderer.CommandCreate, err).Message})
+		return
+	}
+	writeJSON(w, model.PostActionIntegrationResponse{})
+}
+
+func (h *Handler) submitDialog(w http.ResponseWriter, r *http.Request) {
+	var req model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if req.Cancelled {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	channelID, ok := h.verifyState(req.State, req.UserId)
+	if !ok {
+		writeJSON(w, model.SubmitDialogResponse{Error: renderer.DialogExpired})
+		return
+	}
+	actor := service.Actor{UserID: req.UserId, ChannelID: channelID}
+
+	poll, fieldErrors := pollFromDialog(req.Submission)
+	if len(fieldErrors) > 0 {
+		writeJSON(w, model.SubmitDialogResponse{Errors: fieldErrors})
+		return
+	}
+
+	poll, err := h.polls.Create(r.Context(), actor, poll)
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		field := validationErr.Field
+		if element, ok := dialogFields[field]; ok {
+			field = element
+		}
+		writeJSON(w, model.SubmitDialogResponse{Errors: map[string]string{field: validationErr.Message}})
+		return
+	case err != nil:
+		h.log.Error("The poll dialog has failed",
+			slog.String("user_id", req.UserId),
+			slog.String("error", err.Error()),
+		)
+		writeJSON(w, model.SubmitDialogResponse{Error: renderer.Error(renderer.CommandCreate, err).Message})
+		return
+	}
+
+	h.polls.Announce(context.WithoutCancel(r.Context()), poll)
+	w.WriteHeader(http.StatusOK)
+}
+
+// pollFromDialog applies the dialog elements to a new poll with the rules
+// of the /create flags and collects the errors of every element.
+func pollFromDialog(submission map[string]any) (*models.Poll, map[string]string) {
+	poll := &models.Poll{}
+	errs := make(map[string]string)
+
+	poll.Question, _ = submission["question"].(string)
+	options, _ := submission["options"].(string)
+	for _, option := range strings.Split(options, "\n") {
+		if option = strings.TrimSpace(option); option != "" {
+			poll.Options = append(poll.Options, option)
+		}
+	}
+
+	for _, name := range []string{"results", "open", "voters", "deadline", "remind", "quorum", "threshold"} {
+		value := strings.TrimSpace(dialogValue(submission[name]))
+		if value == "" {
+			continue
+		}
+		var validationErr *service.ValidationError
+		if err := service.ApplyPollFlag(poll, name, value); errors.As(err, &validationErr) {
+			errs[name] = validationErr.Message
+		}
+	}
+	for _, name := range []string{"anonymous", "locked"} {
+		if on, _ := submission[name].(bool); on {
+			service.ApplyPollFlag(poll, name, "")
+		}
+	}
+	return poll, errs
+}
+
+// dialogValue returns the text of an element, number elements may be
+// submitted as numbers.
+func dialogValue(v any) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+// signState binds the dialog to the user and the channel it was requested
+// in, so the callbacks cannot be forged for someone else.
+func (h *Handler) signState(actor service.Actor) string {
+	expires := strconv.FormatInt(time.Now().Add(dialogTTL).Unix(), 10)
+	payload := actor.UserID + ":" + actor.ChannelID + ":" + expires
+	return payload + ":" + h.stateSignature(payload)
+}
+
+// verifyState checks the signed state of the user and returns the channel
+// of the dialog.
+func (h *Handler) verifyState(state, userID string) (string, bool) {
+	parts := strings.Split(state, ":")
+	if len(parts) != 4 || parts[0] != userID {
+		return "", false
+	}
+	payload := strings.Join(parts[:3], ":")
+	if !hmac.Equal([]byte(parts[3]), []byte(h.stateSignature(payload))) {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func (h *Handler) stateSignature(payload string) string {
+	mac := hmac.New(sha256.New, h.dialogKey)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -2,91 +2,345 @@ package handlers
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"github.com/mattermost/mattermost/server/public/model"
 	"golang.org/x/exp/slog"
 	"regexp"
 	"strings"
+	"votty/internal/renderer"
 	"votty/internal/service"
-	"votty/internal/storage/tarantool"
 )
 
+// pollRef matches a poll ID, a channel-local number like #3 or last. The
+// commands that may omit it take the poll from the thread of its post.
+const pollRef = `(#[0-9]+|[a-zA-Z0-9_-]+)`
+
 var (
+	createDialogRegex   = regexp.MustCompile(`^/create$`)
+	quickPollRegex      = regexp.MustCompile(`^/quick\s+(.+?)((?:\s+:[a-zA-Z0-9_+-]+:){2,})$`)
 	createPollRegex     = regexp.MustCompile(`^/create\s+([^|]+)\s*\|\s*([^|]+(?:\s*\|\s*[^|]+)*)$`)
-	deleteCommandRegex  = regexp.MustCompile(`^/delete\s+([a-zA-Z0-9_-]+)$`)
-	endCommandRegex     = regexp.MustCompile(`^/end\s+([a-zA-Z0-9_-]+)$`)
-	resultsCommandRegex = regexp.MustCompile(`^/results\s+([a-zA-Z0-9_-]+)$`)
-	voteCommandRegex    = regexp.MustCompile(`^/vote\s+([a-zA-Z0-9_-]+)\s+([1-9][0-9]*)$`)
+	deleteCommandRegex  = regexp.MustCompile(`^/delete\s+` + pollRef + `$`)
+	undeleteRegex       = regexp.MustCompile(`^/undelete\s+` + pollRef + `$`)
+	trashCommandRegex   = regexp.MustCompile(`^/trash$`)
+	retentionRegex      = regexp.MustCompile(`^/retention$`)
+	gdprExportRegex     = regexp.MustCompile(`^/gdpr\s+export\s+(\S+)$`)
+	gdprEraseRegex      = regexp.MustCompile(`^/gdpr\s+erase\s+(\S+)((?:\s+--\S+)*)$`)
+	endCommandRegex     = regexp.MustCompile(`^/end(?:\s+` + pollRef + `)?$`)
+	resultsCommandRegex = regexp.MustCompile(`^/results(?:\s+` + pollRef + `)?(?:\s+(--force))?$`)
+	voteCommandRegex    = regexp.MustCompile(`^/vote(?:\s+` + pollRef + `)?\s+([1-9][0-9]*)$`)
+	remindCommandRegex  = regexp.MustCompile(`^/remind(?:\s+` + pollRef + `)?$`)
+	remindersRegex      = regexp.MustCompile(`^/reminders\s+(on|off)$`)
+	editCommandRegex    = regexp.MustCompile(`^/edit(?:\s+` + pollRef + `)?\s+(question|add|rename|remove)\s+(.+)$`)
+	editRenameRegex     = regexp.MustCompile(`^([1-9][0-9]*)\s+(.+)$`)
+	editRemoveRegex     = regexp.MustCompile(`^([1-9][0-9]*)(?:\s+(--clear))?$`)
+	suggestRegex        = regexp.MustCompile(`^/suggest\s+` + pollRef + `\s+(.+)$`)
+	suggestionRegex     = regexp.MustCompile(`^/suggest\s+(approve|reject)\s+([a-zA-Z0-9_-]+)$`)
+	recountCommandRegex = regexp.MustCompile(`^/recount(?:\s+` + pollRef + `)?$`)
+	templateCreateRegex = regexp.MustCompile(`^/create\s+--template(?:=|\s+)([^\s|]+)$`)
+	templateSaveRegex   = regexp.MustCompile(`^/template\s+save\s+([^|]+)\s*\|\s*([^|]+)\s*\|\s*([^|]+(?:\s*\|\s*[^|]+)*)$`)
+	templateListRegex   = regexp.MustCompile(`^/template\s+list$`)
+	templateDeleteRegex = regexp.MustCompile(`^/template\s+delete\s+(?:(--team)\s+)?(\S+)$`)
+	scheduleAddRegex    = regexp.MustCompile(`^/schedule\s+add\s+([^|]+)\|\s*(\S+(?:\s+\S+){4})\s*$`)
+	scheduleListRegex   = regexp.MustCompile(`^/schedule\s+list$`)
+	scheduleChangeRegex = regexp.MustCompile(`^/schedule\s+(pause|resume|delete)\s+([a-zA-Z0-9_-]+)$`)
 )
 
-func PostHandler(ctx context.Context, storage *tarantool.Storage, log *slog.Logger, client *model.Client4, event *model.WebSocketEvent, botID string) {
+// Handler turns the chat commands into calls of the service layer and
+// replies with the rendered results.
+type Handler struct {
+	log       *slog.Logger
+	client    *model.Client4
+	polls     *service.Polls
+	templates *service.Templates
+	schedules *service.Schedules
+	retention *service.Retention
+	privacy   *service.Privacy
+	users     *renderer.Users
+	// public holds the message kinds posted to the channel, the other
+	// replies are shown only to the user who sent the command.
+	public map[string]bool
+	// dialogURL is the base address of the dialog callbacks, dialogKey
+	// signs the dialog state.
+	dialogURL string
+	dialogKey []byte
+	botID     string
+}
+
+func New(log *slog.Logger, client *model.Client4, polls *service.Polls, templates *service.Templates, schedules *service.Schedules, retention *service.Retention, privacy *service.Privacy, users *renderer.Users, publicReplies []string, botURL, botID string) *Handler {
+	public := make(map[string]bool, len(publicReplies))
+	for _, kind := range publicReplies {
+		public[kind] = true
+	}
+
+	// the key lives as long as the process, dialogs opened before a restart
+	// have to be opened again
+	dialogKey := make([]byte, 32)
+	rand.Read(dialogKey)
+
+	return &Handler{log, client, polls, templates, schedules, retention, privacy, users, public, botURL + dialogPath, dialogKey, botID}
+}
+
+func (h *Handler) Post(ctx context.Context, event *model.WebSocketEvent) {
 	postData, ok := event.GetData()["post"].(string)
 	if !ok {
-		log.Warn("Failed to process event: invalid data type for 'post'.")
+		h.log.Warn("Failed to process event: invalid data type for 'post'.")
 		return
 	}
 	post := &model.Post{}
 
 	err := json.Unmarshal([]byte(postData), &post)
 	if err != nil {
-		log.Warn("Failed to parse 'post' data: ", err.Error())
+		h.log.Warn("Failed to parse 'post' data: ", err.Error())
+		return
+	}
+	if post.UserId == h.botID {
+		return
+	}
+
+	r := h.command(ctx, post)
+	if r == nil {
+		return
+	}
+	public := h.public[renderer.Kind(r)]
+	h.reply(ctx, post, r, public)
+	if public {
+		h.postToOrigin(ctx, post, r)
+	}
+}
+
+// reply answers the command in its channel and thread. A personal reply
+// is never posted to the channel: when it cannot be shown as an ephemeral
+// message, it is sent in the direct channel with the user.
+func (h *Handler) reply(ctx context.Context, post *model.Post, r *model.Post, public bool) {
+	r.ChannelId = post.ChannelId
+	r.RootId = post.RootId
+
+	if !public {
+		_, _, err := h.client.CreatePostEphemeral(ctx, &model.PostEphemeral{UserID: post.UserId, Post: r})
+		if err == nil {
+			return
+		}
+		// the bot may lack the permission to post ephemeral messages
+		h.log.Warn("Failed to send the ephemeral message, sending it directly",
+			slog.String("user_id", post.UserId),
+			slog.String("error", err.Error()),
+		)
+
+		channel, _, err := h.client.CreateDirectChannel(ctx, h.botID, post.UserId)
+		if err != nil {
+			h.log.Error("Failed to open a direct channel, dropping the reply",
+				slog.String("user_id", post.UserId),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+		if channel.Id != post.ChannelId {
+			r.ChannelId = channel.Id
+			r.RootId = ""
+		}
+	}
+
+	created, _, err := h.client.CreatePost(ctx, r)
+	if err != nil {
+		h.log.Error("Failed to send the message",
+			slog.String("user_id", post.UserId),
+			slog.String("message", post.Message),
+		)
+		return
+	}
+	if pollID, ok := r.GetProp(renderer.PollProp).(string); ok && public && renderer.Kind(r) == renderer.MessageAnnouncement {
+		// a poll created in a thread is referred to by the thread root
+		rootID := created.RootId
+		if rootID == "" {
+			rootID = created.Id
+		}
+		h.polls.AttachPost(pollID, rootID)
+	}
+}
+
+// postToOrigin repeats a public reply about a poll in the poll channel
+// when the command was sent elsewhere, for example in the direct channel
+// with the bot.
+func (h *Handler) postToOrigin(ctx context.Context, post *model.Post, r *model.Post) {
+	pollID, ok := r.GetProp(renderer.PollProp).(string)
+	if !ok {
+		return
+	}
+	poll, err := h.polls.Get(pollID)
+	if err != nil {
+		h.log.Warn("Failed to find the channel of the poll",
+			slog.String("pollID", pollID),
+			slog.String("error", err.Error()),
+		)
 		return
 	}
-	if post.UserId == botID {
+	if poll.ChannelID == "" || poll.ChannelID == post.ChannelId {
 		return
 	}
 
-	var r *model.Post
+	origin := &model.Post{
+		ChannelId: poll.ChannelID,
+		RootId:    poll.PostID,
+		Message:   r.Message,
+	}
+	if _, _, err = h.client.CreatePost(ctx, origin); err != nil {
+		h.log.Error("Failed to send the message to the poll channel",
+			slog.String("pollID", pollID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (h *Handler) command(ctx context.Context, post *model.Post) *model.Post {
+	actor := service.Actor{UserID: post.UserId, ChannelID: post.ChannelId, RootID: post.RootId}
+
 	switch {
+	case templateCreateRegex.MatchString(post.Message):
+		matches := templateCreateRegex.FindStringSubmatch(post.Message)
+
+		return h.createFromTemplate(ctx, actor, post, matches)
+
+	case createDialogRegex.MatchString(post.Message):
+		return h.createDialog(actor)
+
+	case strings.HasPrefix(post.Message, "/quick"):
+		matches := quickPollRegex.FindStringSubmatch(post.Message)
+
+		return h.quick(ctx, actor, post, matches)
+
 	case strings.HasPrefix(post.Message, "/create"):
 		matches := createPollRegex.FindStringSubmatch(post.Message)
 
-		r = service.CreatePoll(log, storage, post, matches)
+		return h.create(ctx, actor, post, matches)
+
+	case templateListRegex.MatchString(post.Message):
+		return h.listTemplates(ctx, actor, post)
+
+	case strings.HasPrefix(post.Message, "/template delete"):
+		matches := templateDeleteRegex.FindStringSubmatch(post.Message)
+
+		return h.deleteTemplate(ctx, actor, post, matches)
+
+	case strings.HasPrefix(post.Message, "/template"):
+		matches := templateSaveRegex.FindStringSubmatch(post.Message)
+
+		return h.saveTemplate(ctx, actor, post, matches)
 
 	case strings.HasPrefix(post.Message, "/vote"):
 		matches := voteCommandRegex.FindStringSubmatch(post.Message)
 
-		r = service.Vote(storage, log, post, matches)
+		return h.vote(ctx, actor, post, matches)
 
 	case strings.HasPrefix(post.Message, "/end"):
 		matches := endCommandRegex.FindStringSubmatch(post.Message)
 
-		r = service.EndPoll(storage, log, post, matches)
+		return h.end(actor, post, matches)
 
 	case strings.HasPrefix(post.Message, "/delete"):
 		matches := deleteCommandRegex.FindStringSubmatch(post.Message)
 
-		r = service.DeletePoll(storage, log, post, matches)
+		return h.delete(actor, post, matches)
+
+	case strings.HasPrefix(post.Message, "/undelete"):
+		matches := undeleteRegex.FindStringSubmatch(post.Message)
+
+		return h.undelete(actor, post, matches)
+
+	case trashCommandRegex.MatchString(post.Message):
+		return h.trash(actor, post)
 
 	case strings.HasPrefix(post.Message, "/results"):
 		matches := resultsCommandRegex.FindStringSubmatch(post.Message)
 
-		r = service.PullResults(log, storage, post, matches)
+		return h.results(ctx, actor, post, matches)
 
-	case strings.HasPrefix(post.Message, "/guide"):
-		r = &model.Post{
-			Message: "Привет! Меня зовут Вотти, я помогу тебе проводить опросы быстро и эффективно." +
-				"\nВот основные команды:" +
-				"\nЧтобы создать новый опрос нужно ввести ```/create Ok? | var1 | var2 | var3```, где ```Ok?``` – любой вопрос по твоему усмотрению, ```var1, var2...``` – варианты ответов" +
-				"\nПример: ```/create Это понятный пример? | Да | Нет``` этот запрос вернет тебе пронумерованные варианты ответов и ID опроса " +
-				"\nВсе участники (в том числе и ты), которые получат доступ к ID опроса (PollID) могут проголосовать с помощью команды ```/vote PollID 1```, где ```PollID``` – полученный ID в /create (в след. примерах тоже)" +
-				"\nЕще все могут посмотреть результаты опроса с помощью команды ```/results PollID```" +
-				"\nЕсли ты собрал достаточно голосов, то можно завершить опрос командой ```/end PollID``` и тогда можно будет по прежнему смотреть результаты командой ```/results```, но ```vote``` перестанет быть доступным" +
-				"\nЕсли результат опроса больше не интересен, то можно удалить опрос командой ```/delete PollID```",
-		}
+	case strings.HasPrefix(post.Message, "/schedule add"):
+		matches := scheduleAddRegex.FindStringSubmatch(post.Message)
 
-	}
+		return h.addSchedule(ctx, actor, post, matches)
 
-	if r != nil {
-		r.ChannelId = post.ChannelId
-		_, _, err = client.CreatePost(ctx, r)
+	case scheduleListRegex.MatchString(post.Message):
+		return h.listSchedules(actor, post)
 
-		if err != nil {
-			slog.Error("Failed to send the message",
-				slog.String("user_id", post.UserId),
-				slog.String("message", post.Message),
-			)
-		}
+	case strings.HasPrefix(post.Message, "/schedule"):
+		matches := scheduleChangeRegex.FindStringSubmatch(post.Message)
+
+		return h.changeSchedule(actor, post, matches)
+
+	case strings.HasPrefix(post.Message, "/reminders"):
+		matches := remindersRegex.FindStringSubmatch(post.Message)
+
+		return h.reminders(actor, post, matches)
+
+	case strings.HasPrefix(post.Message, "/remind"):
+		matches := remindCommandRegex.FindStringSubmatch(post.Message)
+
+		return h.remind(ctx, actor, post, matches)
+
+	case strings.HasPrefix(post.Message, "/edit"):
+		matches := editCommandRegex.FindStringSubmatch(post.Message)
+
+		return h.edit(ctx, actor, post, matches)
+
+	case suggestionRegex.MatchString(post.Message):
+		matches := suggestionRegex.FindStringSubmatch(post.Message)
+
+		return h.decideSuggestion(ctx, actor, post, matches)
+
+	case strings.HasPrefix(post.Message, "/suggest"):
+		matches := suggestRegex.FindStringSubmatch(post.Message)
+
+		return h.suggest(ctx, actor, post, matches)
+
+	case strings.HasPrefix(post.Message, "/recount"):
+		matches := recountCommandRegex.FindStringSubmatch(post.Message)
+
+		return h.recount(actor, post, matches)
+
+	case retentionRegex.MatchString(post.Message):
+		return h.retentionReport(ctx, actor, post)
+
+	case strings.HasPrefix(post.Message, "/gdpr export"):
+		matches := gdprExportRegex.FindStringSubmatch(post.Message)
+
+		return h.exportUser(ctx, actor, post, matches)
+
+	case strings.HasPrefix(post.Message, "/gdpr"):
+		matches := gdprEraseRegex.FindStringSubmatch(post.Message)
+
+		return h.eraseUser(ctx, actor, post, matches)
+
+	case strings.HasPrefix(post.Message, "/guide"):
+		return renderer.Guide()
 	}
+	return nil
+}
 
+// usage logs a command that does not match its format and describes the
+// format to the user.
+func (h *Handler) usage(command string, post *model.Post) *model.Post {
+	h.log.Warn("Failed to parse the "+command+" command",
+		slog.String("user_id", post.UserId),
+		slog.String("message", post.Message),
+	)
+	return renderer.Usage(command)
+}
+
+// fail logs the error of the command and describes it to the user.
+func (h *Handler) fail(command string, post *model.Post, err error) *model.Post {
+	if renderer.Expected(err) {
+		h.log.Warn("The "+command+" command has been rejected",
+			slog.String("user_id", post.UserId),
+			slog.String("message", post.Message),
+			slog.String("error", err.Error()),
+		)
+	} else {
+		h.log.Error("The "+command+" command has failed",
+			slog.String("user_id", post.UserId),
+			slog.String("message", post.Message),
+			slog.String("error", err.Error()),
+		)
+	}
+	return renderer.Error(command, err)
 }
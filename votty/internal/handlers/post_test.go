@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestCommandRegexes(t *testing.T) {
+	tests := []struct {
+		name    string
+		regex   *regexp.Regexp
+		message string
+		// want are the submatches without the whole match, nil when the
+		// message must not match
+		want []string
+	}{
+		{name: "vote in thread", regex: voteCommandRegex, message: "/vote 2", want: []string{"", "2"}},
+		{name: "vote by ID", regex: voteCommandRegex, message: "/vote V1StGXR8_Z 2", want: []string{"V1StGXR8_Z", "2"}},
+		{name: "vote by number", regex: voteCommandRegex, message: "/vote #12 3", want: []string{"#12", "3"}},
+		{name: "vote last", regex: voteCommandRegex, message: "/vote last 1", want: []string{"last", "1"}},
+		{name: "vote zero", regex: voteCommandRegex, message: "/vote #1 0"},
+		{name: "vote bad number", regex: voteCommandRegex, message: "/vote #x 1"},
+		{name: "end in thread", regex: endCommandRegex, message: "/end", want: []string{""}},
+		{name: "end by number", regex: endCommandRegex, message: "/end #3", want: []string{"#3"}},
+		{name: "results forced", regex: resultsCommandRegex, message: "/results #3 --force", want: []string{"#3", "--force"}},
+		{name: "results in thread", regex: resultsCommandRegex, message: "/results", want: []string{"", ""}},
+		{name: "delete needs ref", regex: deleteCommandRegex, message: "/delete"},
+		{name: "delete by number", regex: deleteCommandRegex, message: "/delete #4", want: []string{"#4"}},
+		{name: "edit in thread", regex: editCommandRegex, message: "/edit add Tacos", want: []string{"", "add", "Tacos"}},
+		{name: "edit by number", regex: editCommandRegex, message: "/edit #2 rename 1 Big pizza", want: []string{"#2", "rename", "1 Big pizza"}},
+		{name: "edit unknown action", regex: editCommandRegex, message: "/edit #2 drop 1"},
+		{name: "rename", regex: editRenameRegex, message: "2 Big pizza", want: []string{"2", "Big pizza"}},
+		{name: "remove with clear", regex: editRemoveRegex, message: "2 --clear", want: []string{"2", "--clear"}},
+		{name: "remove", regex: editRemoveRegex, message: "2", want: []string{"2", ""}},
+		{name: "suggest", regex: suggestRegex, message: "/suggest #2 Tacos al pastor", want: []string{"#2", "Tacos al pastor"}},
+		{name: "suggest needs ref", regex: suggestRegex, message: "/suggest Tacos"},
+		{name: "approve", regex: suggestionRegex, message: "/suggest approve abc_12-x", want: []string{"approve", "abc_12-x"}},
+		{name: "reject", regex: suggestionRegex, message: "/suggest reject abc", want: []string{"reject", "abc"}},
+		{name: "approve needs ID", regex: suggestionRegex, message: "/suggest approve"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := tt.regex.FindStringSubmatch(tt.message)
+			var got []string
+			if matches != nil {
+				got = matches[1:]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s matched %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"strings"
+	"votty/internal/renderer"
+	"votty/internal/service"
+)
+
+func (h *Handler) exportUser(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 2 {
+		return h.usage(renderer.CommandGDPRExport, post)
+	}
+
+	data, err := h.privacy.Export(ctx, actor, parts[1])
+	if err != nil {
+		return h.fail(renderer.CommandGDPRExport, post, err)
+	}
+
+	if err = h.sendExport(ctx, actor, data); err != nil {
+		return h.fail(renderer.CommandGDPRExport, post, err)
+	}
+	return renderer.ExportSent(data, h.users.Names(ctx, data.UserID))
+}
+
+// sendExport sends the exported data as a JSON file in a direct message,
+// so it never shows up in the channel where the command was run.
+func (h *Handler) sendExport(ctx context.Context, actor service.Actor, data *service.UserData) error {
+	payload, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	channel, _, err := h.client.CreateDirectChannel(ctx, h.botID, actor.UserID)
+	if err != nil {
+		return err
+	}
+
+	upload, _, err := h.client.UploadFile(ctx, payload, channel.Id, fmt.Sprintf("votty-%s.json", data.UserID))
+	if err != nil {
+		return err
+	}
+
+	r := renderer.Export(data, h.users.Names(ctx, data.UserID))
+	r.ChannelId = channel.Id
+	for _, info := range upload.FileInfos {
+		r.FileIds = append(r.FileIds, info.Id)
+	}
+	_, _, err = h.client.CreatePost(ctx, r)
+	return err
+}
+
+func (h *Handler) eraseUser(ctx context.Context, actor service.Actor, post *model.Post, parts []string) *model.Post {
+	if len(parts) < 3 {
+		return h.usage(renderer.CommandGDPRErase, post)
+	}
+
+	opts := service.EraseOptions{AnonymizeVotes: true}
+	for _, token := range strings.Fields(parts[2]) {
+		name, value, _ := strings.Cut(strings.TrimPrefix(token, "--"), "=")
+		switch {
+		case name == "reassign" && value != "":
+			opts.ReassignTo = value
+		case name == "votes" && (value == "anonymize" || value == "delete"):
+			opts.AnonymizeVotes = value == "anonymize"
+		default:
+			return h.usage(renderer.CommandGDPRErase, post)
+		}
+	}
+
+	report, err := h.privacy.Erase(ctx, actor, parts[1], opts)
+	if err != nil {
+		return h.fail(renderer.CommandGDPRErase, post, err)
+	}
+	return renderer.Erased(report, h.users.Names(ctx, report.UserID, report.ReassignedTo))
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mattermost/mattermost/server/public/model"
+	"votty/internal/renderer"
+	"votty/internal/service"
+)
+
+// Reaction votes in quick polls with the reactions to their posts.
+func (h *Handler) Reaction(ctx context.Context, event *model.WebSocketEvent) {
+	reactionData, ok := event.GetData()["reaction"].(string)
+	if !ok {
+		h.log.Warn("Failed to process event: invalid data type for 'reaction'.")
+		return
+	}
+	reaction := &model.Reaction{}
+
+	if err := json.Unmarshal([]byte(reactionData), reaction); err != nil {
+		h.log.Warn("Failed to parse 'reaction' data: ", err.Error())
+		return
+	}
+	if reaction.UserId == h.botID {
+		return
+	}
+
+	channelID := reaction.ChannelId
+	if channelID == "" {
+		channelID = event.GetBroadcast().ChannelId
+	}
+	actor := service.Actor{UserID: reaction.UserId, ChannelID: channelID}
+	added := event.EventType() == model.WebsocketEventReactionAdded
+
+	err := h.polls.React(ctx, actor, reaction.PostId, reaction.EmojiName, added)
+	if err == nil {
+		return
+	}
+
+	// the reaction has no reply, so the rejection is sent to the user alone
+	post := &model.Post{UserId: reaction.UserId, ChannelId: channelID, Message: ":" + reaction.EmojiName + ":"}
+	h.reply(ctx, post, h.fail(renderer.CommandVote, post, err), false)
+}
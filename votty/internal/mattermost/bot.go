@@ -1,6 +1,7 @@
 package mattermost
 
 import (
+	"context"
 	"github.com/mattermost/mattermost/server/public/model"
 	"golang.org/x/exp/slog"
 	"strings"
@@ -10,6 +11,8 @@ import (
 type Bot struct {
 	WebSocketClient *model.WebSocketClient
 	APIv4Client     *model.Client4
+	// UserID is the Mattermost ID of the bot account.
+	UserID string
 }
 
 func New(log *slog.Logger, cfg *config.Config) *Bot {
@@ -17,6 +20,12 @@ func New(log *slog.Logger, cfg *config.Config) *Bot {
 
 	client.SetOAuthToken(cfg.BotToken)
 
+	me, _, err := client.GetUser(context.Background(), "me", "")
+	if err != nil {
+		log.Error("Failed to retrieve bot data", slog.String("error", err.Error()))
+		return nil
+	}
+
 	webSocketClient, err := model.NewWebSocketClient4(strings.Replace(cfg.MattermostURL, "http", "ws", 1), client.AuthToken)
 	if err != nil {
 		log.Error("Mattermost bot initialization failed", err)
@@ -27,5 +36,6 @@ func New(log *slog.Logger, cfg *config.Config) *Bot {
 	return &Bot{
 		webSocketClient,
 		client,
+		me.Id,
 	}
 }
@@ -0,0 +1,174 @@
+package mattermost
+
+import (
+	"context"
+	"github.com/mattermost/mattermost/server/public/model"
+	"golang.org/x/exp/slog"
+	"time"
+	"votty/internal/models"
+	"votty/internal/renderer"
+	"votty/internal/service"
+)
+
+// reminderInterval limits how often the bot sends direct messages.
+const reminderInterval = 200 * time.Millisecond
+
+// reminderThrottle is shared by all reminder senders, so the overall DM
+// rate stays bounded even when several polls are reminded at once.
+var reminderThrottle = time.Tick(reminderInterval)
+
+// Notifier posts the messages of the service to Mattermost.
+type Notifier struct {
+	log    *slog.Logger
+	client *model.Client4
+	users  *renderer.Users
+	botID  string
+}
+
+func NewNotifier(log *slog.Logger, bot *Bot, users *renderer.Users) *Notifier {
+	return &Notifier{log, bot.APIv4Client, users, bot.UserID}
+}
+
+func (n *Notifier) PollCreated(ctx context.Context, poll *models.Poll) string {
+	created := n.post(ctx, poll, renderer.PollCreated(poll))
+	if created == nil {
+		return ""
+	}
+	if poll.Reactions != models.ReactionsOff {
+		n.addReactions(ctx, poll, created.Id)
+	}
+	return created.Id
+}
+
+// addReactions adds the option emojis of a quick poll to its post, so
+// that voting takes a single click.
+func (n *Notifier) addReactions(ctx context.Context, poll *models.Poll, postID string) {
+	for _, emoji := range poll.Options {
+		_, _, err := n.client.SaveReaction(ctx, &model.Reaction{UserId: n.botID, PostId: postID, EmojiName: emoji})
+		if err != nil {
+			n.log.Error("Failed to add the reaction",
+				slog.String("pollID", poll.ID),
+				slog.String("emoji", emoji),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+func (n *Notifier) PollClosed(ctx context.Context, results *service.Results, reason service.CloseReason) {
+	n.post(ctx, results.Poll, renderer.PollOutcome(results, reason, n.users.Names(ctx, results.Poll.OwnerID)))
+}
+
+func (n *Notifier) Remind(ctx context.Context, poll *models.Poll, userIDs []string) {
+	n.direct(ctx, slog.String("pollID", poll.ID), userIDs, func() *model.Post {
+		return renderer.Reminder(poll)
+	})
+}
+
+func (n *Notifier) ScheduleFailed(ctx context.Context, sc *models.Schedule, err error) {
+	n.direct(ctx, slog.String("scheduleID", sc.ID), []string{sc.OwnerID}, func() *model.Post {
+		return renderer.ScheduleFailed(sc, err)
+	})
+}
+
+// direct sends each user a direct message, throttled by reminderThrottle.
+// The about attribute names what the message is about in the logs.
+func (n *Notifier) direct(ctx context.Context, about slog.Attr, userIDs []string, message func() *model.Post) {
+	for _, userID := range userIDs {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reminderThrottle:
+		}
+
+		channel, _, err := n.client.CreateDirectChannel(ctx, n.botID, userID)
+		if err != nil {
+			n.log.Error("Failed to open a direct channel",
+				slog.String("user_id", userID),
+				about,
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+
+		r := message()
+		r.ChannelId = channel.Id
+		if _, _, err = n.client.CreatePost(ctx, r); err != nil {
+			n.log.Error("Failed to send the direct message",
+				slog.String("user_id", userID),
+				about,
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// post sends the message to the poll channel, in the thread of the poll
+// post if there is one, and returns the created post, nil if it has not
+// been sent.
+func (n *Notifier) post(ctx context.Context, poll *models.Poll, r *model.Post) *model.Post {
+	if poll.ChannelID == "" {
+		n.log.Warn("The poll has no channel to post to",
+			slog.String("pollID", poll.ID),
+		)
+		return nil
+	}
+
+	r.ChannelId = poll.ChannelID
+	r.RootId = poll.PostID
+	created, _, err := n.client.CreatePost(ctx, r)
+	if err != nil {
+		n.log.Error("Failed to send the poll message",
+			slog.String("pollID", poll.ID),
+			slog.String("error", err.Error()),
+		)
+		return nil
+	}
+	return created
+}
+
+// PollUpdated rewrites the announcement post of the poll. A thread root
+// the poll was announced in belongs to someone else and is left as is.
+func (n *Notifier) PollUpdated(ctx context.Context, poll *models.Poll) {
+	if poll.PostID == "" {
+		return
+	}
+	post, _, err := n.client.GetPost(ctx, poll.PostID, "")
+	if err != nil {
+		n.log.Error("Failed to get the poll post",
+			slog.String("pollID", poll.ID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if id, _ := post.GetProp(renderer.PollProp).(string); id != poll.ID {
+		return
+	}
+
+	r := renderer.PollCreated(poll)
+	if _, _, err = n.client.PatchPost(ctx, post.Id, &model.PostPatch{Message: &r.Message}); err != nil {
+		n.log.Error("Failed to update the poll post",
+			slog.String("pollID", poll.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (n *Notifier) VotesCleared(ctx context.Context, poll *models.Poll, option string, userIDs []string) {
+	n.direct(ctx, slog.String("pollID", poll.ID), userIDs, func() *model.Post {
+		return renderer.VotesCleared(poll, option)
+	})
+}
+
+func (n *Notifier) OptionSuggested(ctx context.Context, poll *models.Poll, suggestion *models.Suggestion) {
+	names := n.users.Names(ctx, suggestion.UserID)
+	n.direct(ctx, slog.String("pollID", poll.ID), []string{poll.OwnerID}, func() *model.Post {
+		return renderer.OptionSuggested(poll, suggestion, names)
+	})
+}
+
+func (n *Notifier) SuggestionDecided(ctx context.Context, poll *models.Poll, suggestion *models.Suggestion, approved bool) {
+	n.direct(ctx, slog.String("pollID", poll.ID), []string{suggestion.UserID}, func() *model.Post {
+		return renderer.SuggestionAnswered(poll, suggestion, approved)
+	})
+}
@@ -0,0 +1,20 @@
+package models
+
+// Poll lifecycle event types sent to outbound webhooks.
+const (
+	EventPollCreated = "poll.created"
+	EventVoteCast    = "vote.cast"
+	EventVoteChanged = "vote.changed"
+	EventPollClosed  = "poll.closed"
+	EventPollUpdated = "poll.updated"
+)
+
+// Event is the JSON payload of an outbound webhook.
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// CreatedAt is the unix time of the event.
+	CreatedAt int64 `json:"created_at"`
+	Poll      *Poll `json:"poll"`
+	Vote      *Vote `json:"vote,omitempty"`
+}
@@ -1,9 +1,86 @@
 package models
 
+// Results visibility modes of a poll.
+const (
+	ResultsAlways     = "always"
+	ResultsAfterVote  = "after-vote"
+	ResultsAfterClose = "after-close"
+)
+
+// Voter eligibility modes of a poll.
+const (
+	VotersAll     = ""
+	VotersChannel = "channel"
+	VotersUsers   = "users"
+	VotersGroup   = "group"
+)
+
+// Reaction modes of quick polls, voted on with emoji reactions.
+const (
+	ReactionsOff = ""
+	// ReactionsAny counts the latest reaction of the user as the vote.
+	ReactionsAny = "any"
+	// ReactionsSingle also removes the earlier reaction of the user.
+	ReactionsSingle = "single"
+)
+
+// Open options modes, which let voters add options to the poll.
+const (
+	OptionsClosed = ""
+	// OptionsOpen adds the suggested options right away.
+	OptionsOpen = "open"
+	// OptionsApproval adds the suggested options once the owner approves them.
+	OptionsApproval = "approval"
+)
+
 type Poll struct {
-	ID       string   `json:"id"`
-	OwnerID  string   `json:"owner_id"`
-	Question string   `json:"question"`
-	Options  []string `json:"options"`
-	IsActive bool     `json:"is_active"`
+	ID          string   `json:"id"`
+	OwnerID     string   `json:"owner_id"`
+	Question    string   `json:"question"`
+	Options     []string `json:"options"`
+	IsActive    bool     `json:"is_active"`
+	LockedVotes bool     `json:"locked_votes"`
+	// ResultsVisibility is one of the Results* modes, empty means ResultsAlways.
+	ResultsVisibility string `json:"results_visibility"`
+	// ChannelID is the channel where the poll was created.
+	ChannelID string `json:"channel_id"`
+	// Quorum is the number of voters after which the poll closes, 0 disables it.
+	Quorum uint64 `json:"quorum"`
+	// Threshold is the number of votes for a single option after which
+	// the poll closes, 0 disables it.
+	Threshold uint64 `json:"threshold"`
+	// VotersMode is one of the Voters* modes restricting who may vote.
+	VotersMode string `json:"voters_mode"`
+	// Voters holds the user IDs for VotersUsers or the group ID for VotersGroup.
+	Voters []string `json:"voters"`
+	// ClosesAt is the deadline of the poll in unix seconds, 0 means no deadline.
+	ClosesAt int64 `json:"closes_at"`
+	// RemindBefore is how many seconds before the deadline non-voters get
+	// a reminder, 0 disables it.
+	RemindBefore int64 `json:"remind_before"`
+	// Reminded is set once the scheduled reminder has been sent.
+	Reminded bool `json:"reminded"`
+	// Anonymous polls never reveal who voted for what.
+	Anonymous bool `json:"anonymous"`
+	// DeletedAt is when the poll was moved to the trash in unix seconds,
+	// 0 means the poll is not deleted.
+	DeletedAt int64 `json:"deleted_at,omitempty"`
+	// ClosedAt is when the poll was closed in unix seconds, 0 while it is active.
+	ClosedAt int64 `json:"closed_at,omitempty"`
+	// VotesAnonymized is set once the retention policy has removed the
+	// user IDs from the votes.
+	VotesAnonymized bool `json:"votes_anonymized,omitempty"`
+	// Number is the channel-local number of the poll, like #3 in commands,
+	// 0 for polls without a channel.
+	Number uint64 `json:"number,omitempty"`
+	// PostID is the post that announced the poll or the root of the thread
+	// it was announced in, replies in the thread refer to the poll.
+	PostID string `json:"post_id,omitempty"`
+	// Reactions is one of the Reactions* modes, the options of quick polls
+	// are emoji names.
+	Reactions string `json:"reactions,omitempty"`
+	// OpenOptions is one of the Options* modes.
+	OpenOptions string `json:"open_options,omitempty"`
+	// RemindedAt is when the owner last sent the reminders in unix seconds.
+	RemindedAt int64 `json:"reminded_at,omitempty"`
 }
@@ -0,0 +1,20 @@
+package models
+
+// Schedule is a recurring poll created from a template on a cron schedule.
+type Schedule struct {
+	ID        string `json:"id"`
+	OwnerID   string `json:"owner_id"`
+	ChannelID string `json:"channel_id"`
+	// TemplateScopeID and TemplateName reference the template the polls
+	// are created from.
+	TemplateScopeID string `json:"template_scope_id"`
+	TemplateName    string `json:"template_name"`
+	// Cron is a standard five-field cron expression evaluated in Timezone.
+	Cron     string `json:"cron"`
+	Timezone string `json:"timezone"`
+	// CloseAfter is the poll duration in seconds, 0 keeps the polls open.
+	CloseAfter int64 `json:"close_after"`
+	// NextRun is the unix time of the next poll creation.
+	NextRun int64 `json:"next_run"`
+	Paused  bool  `json:"paused"`
+}
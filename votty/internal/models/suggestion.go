@@ -0,0 +1,12 @@
+package models
+
+// Suggestion is an option of an open poll suggested by a voter and waiting
+// for the approval of the poll owner.
+type Suggestion struct {
+	ID     string `json:"id"`
+	PollID string `json:"poll_id"`
+	UserID string `json:"user_id"`
+	Option string `json:"option"`
+	// CreatedAt is when the option was suggested in unix seconds.
+	CreatedAt int64 `json:"created_at"`
+}
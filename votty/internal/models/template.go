@@ -0,0 +1,22 @@
+package models
+
+// Template scopes.
+const (
+	TemplateScopeUser = "user"
+	TemplateScopeTeam = "team"
+)
+
+// Template is a saved poll that can be created again with
+// /create --template NAME.
+type Template struct {
+	// ScopeID is the user ID for TemplateScopeUser or the team ID for
+	// TemplateScopeTeam.
+	ScopeID  string   `json:"scope_id"`
+	Name     string   `json:"name"`
+	Scope    string   `json:"scope"`
+	OwnerID  string   `json:"owner_id"`
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+	// Flags are the /create flags applied to every poll made from the template.
+	Flags []string `json:"flags"`
+}
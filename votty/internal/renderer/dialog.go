@@ -0,0 +1,58 @@
+package renderer
+
+import (
+	"github.com/mattermost/mattermost/server/public/model"
+	"votty/internal/models"
+)
+
+// DialogExpired is shown when the dialog state is forged or too old.
+const DialogExpired = "Форма создания опроса устарела, отправь ```/create``` еще раз"
+
+// CreateButton offers to create a poll in a dialog, the button opens it
+// through the callback at openURL.
+func CreateButton(openURL, state string) *model.Post {
+	r := &model.Post{}
+	model.ParseSlackAttachment(r, []*model.SlackAttachment{{
+		Text: "Опрос с настройками удобнее создать в форме",
+		Actions: []*model.PostAction{{
+			Name: "Создать опрос",
+			Type: model.PostActionTypeButton,
+			Integration: &model.PostActionIntegration{
+				URL:     openURL,
+				Context: map[string]any{"state": state},
+			},
+		}},
+	}})
+	return r
+}
+
+// CreateDialog is the form of a new poll. The names of the settings are
+// the names of the /create flags.
+func CreateDialog(state string) model.Dialog {
+	return model.Dialog{
+		CallbackId:  "create",
+		Title:       "Новый опрос",
+		SubmitLabel: "Создать",
+		State:       state,
+		Elements: []model.DialogElement{
+			{DisplayName: "Вопрос", Name: "question", Type: "text"},
+			{DisplayName: "Варианты ответов", Name: "options", Type: "textarea", HelpText: "Каждый вариант с новой строки"},
+			{DisplayName: "Результаты", Name: "results", Type: "select", Default: models.ResultsAlways, Options: []*model.PostActionOptions{
+				{Text: "Видны всегда", Value: models.ResultsAlways},
+				{Text: "Только проголосовавшим", Value: models.ResultsAfterVote},
+				{Text: "После завершения", Value: models.ResultsAfterClose},
+			}},
+			{DisplayName: "Свои варианты", Name: "open", Type: "select", Optional: true, HelpText: "Могут ли участники добавлять варианты командой /suggest", Options: []*model.PostActionOptions{
+				{Text: "Добавляются сразу", Value: models.OptionsOpen},
+				{Text: "После одобрения", Value: models.OptionsApproval},
+			}},
+			{DisplayName: "Участники", Name: "voters", Type: "text", Optional: true, Placeholder: "channel, @alice,@bob или group:name", HelpText: "Кто может голосовать, по умолчанию все"},
+			{DisplayName: "Срок окончания", Name: "deadline", Type: "text", Optional: true, Placeholder: "24h или 2025-01-31T18:00", HelpText: "Длительность или время в UTC"},
+			{DisplayName: "Напомнить за", Name: "remind", Type: "text", Optional: true, Placeholder: "2h", HelpText: "Только вместе со сроком окончания и участниками канала"},
+			{DisplayName: "Кворум", Name: "quorum", Type: "text", SubType: "number", Optional: true, HelpText: "Завершить опрос, когда проголосует столько участников"},
+			{DisplayName: "Порог", Name: "threshold", Type: "text", SubType: "number", Optional: true, HelpText: "Завершить опрос, когда вариант наберет столько голосов"},
+			{DisplayName: "Анонимный", Name: "anonymous", Type: "bool", Optional: true, Placeholder: "Не показывать, кто за что проголосовал"},
+			{DisplayName: "Без изменения голоса", Name: "locked", Type: "bool", Optional: true, Placeholder: "Голос нельзя изменить после того, как он отдан"},
+		},
+	}
+}
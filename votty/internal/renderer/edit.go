@@ -0,0 +1,40 @@
+package renderer
+
+import (
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"votty/internal/models"
+	"votty/internal/service"
+)
+
+// PollEdited confirms the change of the poll and shows its options.
+func PollEdited(poll *models.Poll) *model.Post {
+	message := fmt.Sprintf("Опрос ```%s``` \"%s\" изменен\nВарианты ответов:\n", pollRef(poll), poll.Question)
+	for i, option := range poll.Options {
+		message += fmt.Sprintf("\t%v. %s\n", i+1, option)
+	}
+	return &model.Post{
+		Message: message,
+	}
+}
+
+// OptionRemoved confirms the removal of the option and the number of the
+// deleted votes for it.
+func OptionRemoved(removed *service.OptionRemoved) *model.Post {
+	message := fmt.Sprintf("Вариант \"%s\" удален", removed.Option)
+	if removed.Cleared > 0 {
+		message += fmt.Sprintf(", удалено голосов: %v, проголосовавшие получат уведомление", removed.Cleared)
+	}
+	r := PollEdited(removed.Poll)
+	r.Message = message + "\n" + r.Message
+	return r
+}
+
+// VotesCleared is the direct message sent to a user whose vote for the
+// removed option has been deleted.
+func VotesCleared(poll *models.Poll, option string) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Вариант \"%s\" удален из опроса \"%s\" (```%s```), поэтому твой голос за него тоже удален\nПроголосовать заново можно командой ```/vote %s N```",
+			option, poll.Question, poll.ID, poll.ID),
+	}
+}
@@ -0,0 +1,33 @@
+package renderer
+
+import "github.com/mattermost/mattermost/server/public/model"
+
+// Guide introduces the bot and lists its commands.
+func Guide() *model.Post {
+	return &model.Post{
+		Message: "Привет! Меня зовут Вотти, я помогу тебе проводить опросы быстро и эффективно." +
+			"\nВот основные команды:" +
+			"\nЧтобы создать новый опрос нужно ввести ```/create Ok? | var1 | var2 | var3```, где ```Ok?``` – любой вопрос по твоему усмотрению, ```var1, var2...``` – варианты ответов" +
+			"\nПример: ```/create Это понятный пример? | Да | Нет``` этот запрос вернет тебе пронумерованные варианты ответов и ID опроса " +
+			"\nЕсли перед вопросом указать флаг ```--locked```, например ```/create --locked Ok? | var1 | var2```, то голос нельзя будет изменить после того, как он отдан" +
+			"\nФлаг ```--results=after-vote``` покажет результаты только тем, кто уже проголосовал, а ```--results=after-close``` – только после завершения опроса. Создатель опроса может посмотреть их в любой момент командой ```/results PollID --force```" +
+			"\nФлаги ```--quorum=8``` и ```--threshold=5``` завершат опрос автоматически, когда проголосуют 8 участников или один из вариантов наберет 5 голосов" +
+			"\nФлаг ```--voters``` ограничивает круг участников: ```--voters=channel``` – только участники канала, ```--voters=@alice,@bob``` – только указанные пользователи, ```--voters=group:developers``` – только участники группы" +
+			"\nФлаг ```--deadline=24h``` (или ```--deadline=2025-01-31T18:00``` в UTC) задает срок окончания опроса, а ```--remind=2h``` напомнит не проголосовавшим участникам канала за 2 часа до него" +
+			"\nВсе участники (в том числе и ты), которые получат доступ к ID опроса (PollID) могут проголосовать с помощью команды ```/vote PollID 1```, где ```PollID``` – полученный ID в /create (в след. примерах тоже)" +
+			"\nВместо ID можно указать номер опроса в канале, например ```/vote #3 1```, или ```last``` для последнего опроса канала, а в ветке сообщения с опросом ID можно не указывать совсем: ```/vote 1```" +
+			"\nЕсли не хочешь голосовать на виду у всех, отправь ```/vote PollID 1``` боту в личные сообщения" +
+			"\nЕще все могут посмотреть результаты опроса с помощью команды ```/results PollID```" +
+			"\nЕсли ты собрал достаточно голосов, то можно завершить опрос командой ```/end PollID``` и тогда можно будет по прежнему смотреть результаты командой ```/results```, но ```vote``` перестанет быть доступным" +
+			"\nСоздатель опроса с ```--voters=channel``` может напомнить не проголосовавшим участникам командой ```/remind PollID```, а отключить такие напоминания для себя можно командой ```/reminders off```" +
+			"\nФлаг ```--anonymous``` скрывает, кто за что проголосовал" +
+			"\nЧасто создаешь одинаковые опросы? Сохрани шаблон командой ```/template save Название | Ok? | var1 | var2``` (флаги опроса и ```--team``` для шаблона команды указываются перед названием), посмотри их через ```/template list``` и создай опрос командой ```/create --template Название```. Удалить шаблон можно командой ```/template delete Название```" +
+			"\nОпрос по шаблону можно создавать автоматически по расписанию: ```/schedule add --tz=Europe/Moscow --close=24h Название | 0 10 * * 5``` создаст опрос каждую пятницу в 10:00 и завершит его через сутки (канал можно указать флагом ```--channel=~town-square```). Посмотреть расписания можно командой ```/schedule list```, а управлять ими – ```/schedule pause|resume|delete ScheduleID```" +
+			"\nОтправь ```/create``` без аргументов, чтобы создать опрос в форме со всеми настройками" +
+			"\nДля простых вопросов есть быстрые опросы с голосованием реакциями: ```/quick Ок? :thumbsup: :thumbsdown: :shrug:```, а с флагом ```--single``` у каждого участника останется только одна реакция" +
+			"\nСоздатель может изменить активный опрос: ```/edit PollID question Новый вопрос?```, ```/edit PollID add Вариант```, ```/edit PollID rename 2 Вариант``` или ```/edit PollID remove 2``` (с флагом ```--clear``` вариант удаляется вместе с голосами)" +
+			"\nВ опросе, созданном с флагом ```--open```, участники могут добавить свой вариант командой ```/suggest PollID Вариант```, а с ```--open=approval``` вариант появится после одобрения создателем" +
+			"\nЕсли результаты опроса вызывают сомнения, создатель может пересчитать их по голосам командой ```/recount PollID```" +
+			"\nЕсли результат опроса больше не интересен, то можно удалить опрос командой ```/delete PollID```. Удаленный опрос попадает в корзину: посмотреть ее можно командой ```/trash```, а восстановить опрос – командой ```/undelete PollID```, пока он не удален навсегда",
+	}
+}
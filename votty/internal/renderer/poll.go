@@ -0,0 +1,277 @@
+package renderer
+
+import (
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"time"
+	"votty/internal/models"
+	"votty/internal/service"
+)
+
+// PollProp is the post property holding the ID of the poll the post is
+// about.
+const PollProp = "votty_poll_id"
+
+// PollCreated describes a new poll and how to vote in it.
+func PollCreated(poll *models.Poll) *model.Post {
+	if poll.Reactions != models.ReactionsOff {
+		return quickPollCreated(poll)
+	}
+
+	message := fmt.Sprintf("Голосование \"%s\" было создано!\nID: ```%s```\n", poll.Question, poll.ID)
+	if poll.Number > 0 {
+		message += fmt.Sprintf("Номер в канале: ```#%v```\n", poll.Number)
+	}
+	message += "Варианты ответов:\n"
+
+	for i, option := range poll.Options {
+		message += fmt.Sprintf("\t%v. %s\n", i+1, option)
+	}
+	if poll.LockedVotes {
+		message += "Голос можно отдать только один раз, изменить его будет нельзя\n"
+	}
+	switch poll.ResultsVisibility {
+	case models.ResultsAfterVote:
+		message += "Результаты будут видны только тем, кто уже проголосовал\n"
+	case models.ResultsAfterClose:
+		message += "Результаты будут видны только после завершения опроса\n"
+	}
+	switch poll.VotersMode {
+	case models.VotersChannel:
+		message += "Голосовать могут только участники этого канала\n"
+	case models.VotersUsers:
+		message += "Голосовать могут только указанные пользователи\n"
+	case models.VotersGroup:
+		message += "Голосовать могут только участники указанной группы\n"
+	}
+	switch poll.OpenOptions {
+	case models.OptionsOpen:
+		message += fmt.Sprintf("Участники могут добавить свой вариант командой ```/suggest %s Вариант```\n", pollRef(poll))
+	case models.OptionsApproval:
+		message += fmt.Sprintf("Участники могут предложить свой вариант командой ```/suggest %s Вариант```, он появится после одобрения создателем\n", pollRef(poll))
+	}
+	if poll.ClosesAt > 0 {
+		message += fmt.Sprintf("Опрос завершится %s\n", formatDeadline(poll.ClosesAt))
+	}
+	if poll.Quorum > 0 {
+		message += fmt.Sprintf("Опрос завершится автоматически, когда проголосуют %v участников\n", poll.Quorum)
+	}
+	if poll.Threshold > 0 {
+		message += fmt.Sprintf("Опрос завершится автоматически, когда один из вариантов наберет %v голосов\n", poll.Threshold)
+	}
+	message += fmt.Sprintf("Перешли это сообщения всем участникам\nНапример, для того чтобы проголосовать за 1 вариант (%v) нужно отправить команду ```/vote %v 1```", poll.Options[0], pollRef(poll))
+	if poll.ChannelID != "" {
+		message += " или ответить в ветке этого сообщения командой ```/vote 1```"
+	}
+
+	return withKind(&model.Post{
+		Message: message,
+		Props:   model.StringInterface{PollProp: poll.ID},
+	}, MessageAnnouncement)
+}
+
+// quickPollCreated describes a poll voted on with reactions to the post.
+func quickPollCreated(poll *models.Poll) *model.Post {
+	message := fmt.Sprintf("%s\n", poll.Question)
+	for _, emoji := range poll.Options {
+		message += fmt.Sprintf(":%s: ", emoji)
+	}
+	message += fmt.Sprintf("\nГолосуй реакцией на это сообщение. Номер опроса: ```%s```", pollRef(poll))
+	if poll.Reactions == models.ReactionsSingle {
+		message += ", учитывается только одна реакция"
+	}
+	if poll.ClosesAt > 0 {
+		message += fmt.Sprintf("\nОпрос завершится %s", formatDeadline(poll.ClosesAt))
+	}
+
+	return withKind(&model.Post{
+		Message: message,
+		Props:   model.StringInterface{PollProp: poll.ID},
+	}, MessageAnnouncement)
+}
+
+// optionLabel shows the 0-based option, the options of quick polls are
+// emojis.
+func optionLabel(poll *models.Poll, i int) string {
+	if poll.Reactions != models.ReactionsOff {
+		return ":" + poll.Options[i] + ":"
+	}
+	return poll.Options[i]
+}
+
+// pollRef is the shortest reference to the poll in its channel.
+func pollRef(poll *models.Poll) string {
+	if poll.Number > 0 {
+		return fmt.Sprintf("#%v", poll.Number)
+	}
+	return poll.ID
+}
+
+// VoteAccepted confirms the vote. Confirmations of anonymous polls are
+// visible to the channel, so they omit the choice.
+func VoteAccepted(result *service.VoteResult) *model.Post {
+	message := fmt.Sprintf("Ты успешно сделал свой голос%s", votedFor(result, ""))
+	switch {
+	case result.Changed:
+		message = fmt.Sprintf("Ты успешно изменил свой выбор%s", votedFor(result, " на"))
+	case result.Poll.LockedVotes:
+		message += ". Изменить его будет нельзя"
+	}
+
+	return &model.Post{
+		Message: message,
+	}
+}
+
+func votedFor(result *service.VoteResult, preposition string) string {
+	if result.Poll.Anonymous {
+		return ""
+	}
+	choice := int(result.Vote.Choice)
+	return fmt.Sprintf("%s %v (%s)", preposition, choice+1, optionLabel(result.Poll, choice))
+}
+
+func PollClosed(poll *models.Poll) *model.Post {
+	return withKind(&model.Post{
+		Message: fmt.Sprintf("Голосование ```%s``` было завершено. Результаты можно получить отправив ```/results %s```", poll.ID, poll.ID),
+		Props:   model.StringInterface{PollProp: poll.ID},
+	}, MessageOutcome)
+}
+
+func PollDeleted(trashed *service.TrashedPoll) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Голосование ```%s``` было удалено. Восстановить его можно командой ```/undelete %s``` до %s",
+			trashed.Poll.ID, trashed.Poll.ID, formatDeadline(trashed.PurgeAt)),
+	}
+}
+
+func PollRestored(poll *models.Poll) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Голосование ```%s``` \"%s\" восстановлено", poll.ID, poll.Question),
+	}
+}
+
+// Trash lists the deleted polls of the user.
+func Trash(trash []*service.TrashedPoll) *model.Post {
+	if len(trash) == 0 {
+		return &model.Post{
+			Message: "Корзина пуста",
+		}
+	}
+
+	message := "Удаленные опросы:\n"
+	for _, t := range trash {
+		message += fmt.Sprintf("\t```%s```: %s, будет удален навсегда %s\n", t.Poll.ID, t.Poll.Question, formatDeadline(t.PurgeAt))
+	}
+	message += "Восстановить опрос можно командой ```/undelete PollID```"
+	return &model.Post{
+		Message: message,
+	}
+}
+
+// Results of a closed poll are final, the results of an active poll are
+// a personal reply.
+func Results(results *service.Results, names Names) *model.Post {
+	kind := MessageResults
+	if !results.Poll.IsActive {
+		kind = MessageOutcome
+	}
+	return withKind(&model.Post{
+		Message: formatResults(results, names),
+	}, kind)
+}
+
+// PollOutcome announces a poll closed automatically and its results.
+func PollOutcome(results *service.Results, reason service.CloseReason, names Names) *model.Post {
+	poll := results.Poll
+
+	var why string
+	switch reason.Kind {
+	case service.CloseQuorum:
+		why = fmt.Sprintf("проголосовали %v из %v участников", results.Voters, poll.Quorum)
+	case service.CloseThreshold:
+		why = fmt.Sprintf("вариант %v (%s) набрал %v голосов", reason.Option, optionLabel(poll, reason.Option-1), results.Votes[reason.Option-1])
+	case service.CloseDeadline:
+		why = "наступил срок окончания опроса"
+	}
+
+	return withKind(&model.Post{
+		Message: fmt.Sprintf("Голосование ```%s``` завершено автоматически: %s\n%s", poll.ID, why, formatResults(results, names)),
+	}, MessageOutcome)
+}
+
+func formatResults(results *service.Results, names Names) string {
+	poll := results.Poll
+	message := fmt.Sprintf("Результаты опроса для ```%s```\nВопрос: %s \nСоздатель: ```%s```\n", poll.ID, poll.Question, names.Of(poll.OwnerID))
+
+	if poll.IsActive {
+		message += "Статус: активен\n"
+	}
+	if !poll.IsActive {
+		message += "Статус: завершен\n"
+	}
+	if poll.IsActive && poll.ClosesAt > 0 {
+		message += fmt.Sprintf("Срок окончания: %s\n", formatDeadline(poll.ClosesAt))
+	}
+	if results.QuorumMet != nil {
+		if *results.QuorumMet {
+			message += fmt.Sprintf("Кворум: %v из %v, достигнут\n", results.Voters, poll.Quorum)
+		} else {
+			message += fmt.Sprintf("Кворум: %v из %v, не достигнут\n", results.Voters, poll.Quorum)
+		}
+	}
+	for i := range poll.Options {
+		message += fmt.Sprintf("\t%v. %s: %v\n", i+1, optionLabel(poll, i), results.Votes[i])
+	}
+	return message
+}
+
+// Recounted reports the rebuild of the poll tallies.
+func Recounted(pollID string, fixed bool) *model.Post {
+	if fixed {
+		return &model.Post{
+			Message: fmt.Sprintf("Голоса опроса ```%s``` пересчитаны, счетчики результатов были исправлены", pollID),
+		}
+	}
+	return &model.Post{
+		Message: fmt.Sprintf("Голоса опроса ```%s``` пересчитаны, расхождений не найдено", pollID),
+	}
+}
+
+func RemindersRequested(recipients int) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Напоминание будет отправлено участникам, которые еще не проголосовали: %v", recipients),
+	}
+}
+
+func RemindersSwitched(enabled bool) *model.Post {
+	if !enabled {
+		return &model.Post{
+			Message: "Напоминания о голосованиях отключены. Включить их снова можно командой ```/reminders on```",
+		}
+	}
+	return &model.Post{
+		Message: "Напоминания о голосованиях включены",
+	}
+}
+
+// Reminder is the direct message sent to a member who has not voted yet.
+func Reminder(poll *models.Poll) *model.Post {
+	message := fmt.Sprintf("Напоминание: ты еще не проголосовал в опросе \"%s\" (```%s```)", poll.Question, poll.ID)
+	if poll.ClosesAt > 0 {
+		message += fmt.Sprintf(", он завершится %s", formatDeadline(poll.ClosesAt))
+	}
+	message += "\nВарианты ответов:\n"
+	for i, option := range poll.Options {
+		message += fmt.Sprintf("\t%v. %s\n", i+1, option)
+	}
+	message += fmt.Sprintf("Проголосовать можно командой ```/vote %s 1```\nОтключить напоминания можно командой ```/reminders off```", poll.ID)
+
+	return &model.Post{
+		Message: message,
+	}
+}
+
+func formatDeadline(closesAt int64) string {
+	return time.Unix(closesAt, 0).UTC().Format("2006-01-02 15:04 UTC")
+}
@@ -0,0 +1,36 @@
+package renderer
+
+import (
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"votty/internal/service"
+)
+
+// Export accompanies the file with the exported data of the user.
+func Export(data *service.UserData, names Names) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Данные пользователя ```%s```: опросов – %v, голосов – %v, шаблонов – %v, расписаний – %v, предложенных вариантов – %v",
+			names.Of(data.UserID), len(data.Polls), len(data.Votes), len(data.Templates), len(data.Schedules), len(data.Suggestions)),
+	}
+}
+
+func ExportSent(data *service.UserData, names Names) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Данные пользователя ```%s``` отправлены тебе в личные сообщения", names.Of(data.UserID)),
+	}
+}
+
+func Erased(report *service.EraseReport, names Names) *model.Post {
+	message := fmt.Sprintf("Данные пользователя ```%s``` удалены:\n", names.Of(report.UserID))
+	if report.ReassignedTo != "" {
+		message += fmt.Sprintf("\tопросов передано пользователю ```%s```: %v\n", names.Of(report.ReassignedTo), report.PollsReassigned)
+	} else {
+		message += fmt.Sprintf("\tопросов удалено: %v\n", report.PollsDeleted)
+	}
+	message += fmt.Sprintf("\tголосов удалено или обезличено: %v\n\tшаблонов удалено: %v\n\tрасписаний удалено: %v\n\tпредложенных вариантов удалено: %v\n\tнеотправленных событий вебхуков обезличено: %v",
+		report.Votes, report.Templates, report.Schedules, report.Suggestions, report.Events)
+
+	return &model.Post{
+		Message: message,
+	}
+}
@@ -0,0 +1,203 @@
+// Package renderer turns the typed results and errors of the service
+// layer into Mattermost posts.
+package renderer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"votty/internal/models"
+	"votty/internal/service"
+)
+
+// Commands, used to pick the usage and error messages.
+const (
+	CommandCreate         = "/create"
+	CommandCreateTemplate = "/create --template"
+	CommandQuick          = "/quick"
+	CommandVote           = "/vote"
+	CommandEnd            = "/end"
+	CommandDelete         = "/delete"
+	CommandUndelete       = "/undelete"
+	CommandResults        = "/results"
+	CommandRecount        = "/recount"
+	CommandRemind         = "/remind"
+	CommandEdit           = "/edit"
+	CommandSuggest        = "/suggest"
+	CommandSuggestion     = "/suggest approve|reject"
+	CommandReminders      = "/reminders"
+	CommandTemplateSave   = "/template save"
+	CommandTemplateDelete = "/template delete"
+	CommandScheduleAdd    = "/schedule add"
+	CommandSchedule       = "/schedule"
+	CommandRetention      = "/retention"
+	CommandGDPRExport     = "/gdpr export"
+	CommandGDPRErase      = "/gdpr erase"
+)
+
+// Message kinds, the reply policy decides which of them are posted to the
+// channel and which are shown only to the user who sent the command.
+const (
+	MessageAnnouncement = "announcement"
+	MessageOutcome      = "outcome"
+	MessageResults      = "results"
+	MessageConfirmation = "confirmation"
+	MessageError        = "error"
+)
+
+// KindProp is the post property holding the message kind.
+const KindProp = "votty_kind"
+
+// Kind returns the message kind of the post, the posts without one are
+// confirmations.
+func Kind(r *model.Post) string {
+	if kind, ok := r.GetProp(KindProp).(string); ok {
+		return kind
+	}
+	return MessageConfirmation
+}
+
+func withKind(r *model.Post, kind string) *model.Post {
+	r.AddProp(KindProp, kind)
+	return r
+}
+
+var usages = map[string]string{
+	CommandCreate:         "Произошла ошибка при обработки команды, запрос на создание должен быть в формате ```/create [--флаги] Вопрос? | Вариант1 | Вариант2 | Вариант3```",
+	CommandCreateTemplate: "Произошла ошибка при обработки команды, запрос должен быть в формате ```/create --template Название```",
+	CommandQuick:          "Произошла ошибка при обработки команды, запрос на быстрый опрос должен быть в формате ```/quick [--single] [--флаги] Вопрос? :thumbsup: :thumbsdown: :shrug:```, нужно хотя бы два эмодзи",
+	CommandVote:           "Произошла ошибка при обработке, команда голосования должна быть в формате ```/vote pollID 1```, где pollID – id опроса, его номер в канале (```#3```) или ```last```",
+	CommandEnd:            "Произошла ошибка при обработки команды, запрос на завершение должен быть в формате ```/end pollID```, где pollID – id опроса, его номер в канале (```#3```) или ```last```",
+	CommandDelete:         "Произошла ошибка при обработки команды, запрос на удаление должен быть в формате ```/delete pollID```, где pollID – id опроса, его номер в канале (```#3```) или ```last```",
+	CommandUndelete:       "Произошла ошибка при обработки команды, запрос на восстановление должен быть в формате ```/undelete pollID```, где pollID – id опроса или его номер в канале (```#3```)",
+	CommandResults:        "Произошла ошибка при обработке, запрос на результаты должен быть в формате ```/results pollID```, где pollID – id опроса, его номер в канале (```#3```) или ```last```",
+	CommandRecount:        "Произошла ошибка при обработки команды, запрос на пересчет должен быть в формате ```/recount pollID```, где pollID – id опроса, его номер в канале (```#3```) или ```last```",
+	CommandRemind:         "Произошла ошибка при обработки команды, запрос на напоминание должен быть в формате ```/remind pollID```, где pollID – id опроса, его номер в канале (```#3```) или ```last```",
+	CommandEdit:           "Произошла ошибка при обработки команды, запрос на изменение опроса должен быть в формате ```/edit pollID question Новый вопрос?```, ```/edit pollID add Вариант```, ```/edit pollID rename 2 Вариант``` или ```/edit pollID remove 2 [--clear]```",
+	CommandSuggest:        "Произошла ошибка при обработки команды, запрос на новый вариант должен быть в формате ```/suggest pollID Вариант```, где pollID – id опроса, его номер в канале (```#3```) или ```last```",
+	CommandSuggestion:     "Произошла ошибка при обработки команды, запрос должен быть в формате ```/suggest approve SuggestionID``` или ```/suggest reject SuggestionID```",
+	CommandReminders:      "Произошла ошибка при обработки команды, запрос должен быть в формате ```/reminders off``` или ```/reminders on```",
+	CommandTemplateSave:   "Произошла ошибка при обработки команды, запрос на сохранение шаблона должен быть в формате ```/template save [--team] [--флаги] Название | Вопрос? | Вариант1 | Вариант2```",
+	CommandTemplateDelete: "Произошла ошибка при обработки команды, запрос на удаление шаблона должен быть в формате ```/template delete [--team] Название```",
+	CommandScheduleAdd:    "Произошла ошибка при обработки команды, запрос на создание расписания должен быть в формате ```/schedule add [--tz=Europe/Moscow] [--channel=~town-square] [--close=24h] Шаблон | 0 10 * * 5```",
+	CommandSchedule:       "Произошла ошибка при обработки команды, запрос должен быть в формате ```/schedule pause|resume|delete ScheduleID```",
+	CommandGDPRExport:     "Произошла ошибка при обработки команды, запрос на выгрузку должен быть в формате ```/gdpr export UserID```, где UserID – ID пользователя или @username",
+	CommandGDPRErase:      "Произошла ошибка при обработки команды, запрос на удаление данных должен быть в формате ```/gdpr erase UserID [--reassign=@username] [--votes=anonymize|delete]```",
+}
+
+// notOwner describes what a user who does not own the poll, template or
+// schedule tried to do.
+var notOwner = map[string]string{
+	CommandEnd:            "Ты не можешь завершить этот опрос, потому что ты не являешься его владельцем",
+	CommandDelete:         "Ты не можешь удалить этот опрос, потому что ты не являешься его владельцем",
+	CommandUndelete:       "Ты не можешь восстановить этот опрос, потому что ты не являешься его владельцем",
+	CommandResults:        "Флаг ```--force``` доступен только создателю опроса",
+	CommandRecount:        "Ты не можешь пересчитать этот опрос, потому что ты не являешься его владельцем",
+	CommandRemind:         "Ты не можешь отправить напоминание, потому что ты не являешься владельцем опроса",
+	CommandEdit:           "Ты не можешь изменить этот опрос, потому что ты не являешься его владельцем",
+	CommandSuggestion:     "Одобрять и отклонять предложенные варианты может только создатель опроса",
+	CommandTemplateSave:   "Шаблон с таким названием уже есть у команды, и ты не можешь его изменить, потому что не являешься его владельцем",
+	CommandTemplateDelete: "Ты не можешь удалить этот шаблон, потому что ты не являешься его владельцем",
+	CommandSchedule:       "Ты не можешь изменить это расписание, потому что ты не являешься его владельцем",
+}
+
+var noTeam = map[string]string{
+	CommandTemplateSave:   "Командные шаблоны можно сохранять только в каналах команды",
+	CommandTemplateDelete: "Командные шаблоны можно удалять только в каналах команды",
+	CommandScheduleAdd:    "Канал можно указать только из канала команды",
+}
+
+// Usage describes the format of the command.
+func Usage(command string) *model.Post {
+	return withKind(&model.Post{
+		Message: usages[command],
+	}, MessageError)
+}
+
+// Error describes the error of the command to the user.
+func Error(command string, err error) *model.Post {
+	var validationErr *service.ValidationError
+	var hiddenErr *service.ResultsHiddenError
+
+	message := "Произошла какая то ошибка :("
+	switch {
+	case errors.As(err, &validationErr):
+		message = fmt.Sprintf("Произошла ошибка при обработки команды: %s", validationErr.Message)
+	case errors.As(err, &hiddenErr):
+		message = "Результаты этого опроса будут доступны после его завершения"
+		if hiddenErr.Visibility == models.ResultsAfterVote {
+			message = "Результаты этого опроса станут доступны после того, как ты проголосуешь"
+		}
+	case errors.Is(err, service.ErrPollNotFound):
+		message = "Такого опроса не существует :("
+	case errors.Is(err, service.ErrTemplateNotFound):
+		message = "Такого шаблона не существует :("
+	case errors.Is(err, service.ErrScheduleNotFound):
+		message = "Такого расписания не существует :("
+	case errors.Is(err, service.ErrNotOwner):
+		message = notOwner[command]
+		if message == "" {
+			message = "Ты не можешь это сделать, потому что ты не являешься владельцем"
+		}
+	case errors.Is(err, service.ErrNotAdmin):
+		message = "Эта команда доступна только системным администраторам"
+	case errors.Is(err, service.ErrNotEligible):
+		message = "Ты не можешь голосовать в этом опросе, потому что не входишь в число его участников"
+		if command == CommandResults {
+			message = "Ты не можешь смотреть результаты этого опроса, потому что не входишь в число его участников"
+		}
+	case errors.Is(err, service.ErrPollClosed):
+		message = "Опрос уже не актуален"
+	case errors.Is(err, service.ErrInvalidOption):
+		message = "Такого варианта не существует в опросе"
+	case errors.Is(err, service.ErrVoteLocked):
+		message = "Ты уже проголосовал в этом опросе, а изменять голос в нем нельзя"
+	case errors.Is(err, service.ErrOptionHasVotes):
+		message = "За этот вариант уже проголосовали. Удалить его вместе с голосами можно командой ```/edit pollID remove N --clear```, проголосовавшие получат уведомление"
+	case errors.Is(err, service.ErrOptionsClosed):
+		message = "В этом опросе нельзя предлагать свои варианты"
+	case errors.Is(err, service.ErrSuggestionNotFound):
+		message = "Такого предложения не существует, возможно, по нему уже приняли решение"
+	case errors.Is(err, service.ErrVoteNotFound):
+		message = "Ты еще не голосовал в этом опросе"
+	case errors.Is(err, service.ErrNotDeleted):
+		message = "Этот опрос не удален"
+	case errors.Is(err, service.ErrNotRemindable):
+		message = "Напоминания можно отправлять только для активных опросов, ограниченных участниками канала (```--voters=channel```)"
+	case errors.Is(err, service.ErrRemindTooSoon):
+		message = "Напоминания по этому опросу уже отправлялись недавно, повторить их можно не чаще раза в час"
+	case errors.Is(err, service.ErrNoTeam):
+		message = noTeam[command]
+		if message == "" {
+			message = "Эта команда доступна только в каналах команды"
+		}
+	}
+
+	return withKind(&model.Post{
+		Message: message,
+	}, MessageError)
+}
+
+// Expected reports whether the error is a typed error of the service,
+// which is the user's mistake rather than a failure of the bot.
+func Expected(err error) bool {
+	var validationErr *service.ValidationError
+	var hiddenErr *service.ResultsHiddenError
+
+	switch {
+	case errors.As(err, &validationErr), errors.As(err, &hiddenErr):
+		return true
+	}
+	for _, target := range []error{
+		service.ErrPollNotFound, service.ErrNotOwner, service.ErrNotAdmin, service.ErrPollClosed,
+		service.ErrInvalidOption, service.ErrNotEligible, service.ErrVoteLocked,
+		service.ErrVoteNotFound, service.ErrNotDeleted, service.ErrNotRemindable, service.ErrTemplateNotFound,
+		service.ErrScheduleNotFound, service.ErrNoTeam, service.ErrOptionHasVotes, service.ErrOptionsClosed,
+		service.ErrSuggestionNotFound, service.ErrRemindTooSoon,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
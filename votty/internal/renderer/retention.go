@@ -0,0 +1,55 @@
+package renderer
+
+import (
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"time"
+	"votty/internal/models"
+	"votty/internal/service"
+)
+
+// RetentionReport describes the retention policy and what its next run
+// would remove.
+func RetentionReport(report *service.RetentionReport) *model.Post {
+	if report.PurgeAfter == 0 && report.AnonymizeAfter == 0 {
+		return &model.Post{
+			Message: "Политика хранения не настроена, завершенные опросы хранятся бессрочно. Ее можно задать переменными ```RETENTION_DAYS``` и ```ANONYMIZE_AFTER_DAYS```",
+		}
+	}
+
+	message := "Политика хранения:\n"
+	if report.PurgeAfter > 0 {
+		message += fmt.Sprintf("\tзавершенные опросы удаляются вместе с голосами через %v дн. после завершения\n", days(report.PurgeAfter))
+	}
+	if report.AnonymizeAfter > 0 {
+		message += fmt.Sprintf("\tголоса в завершенных опросах обезличиваются через %v дн. после завершения\n", days(report.AnonymizeAfter))
+	}
+
+	if len(report.Purge) == 0 && len(report.Anonymize) == 0 {
+		message += "Сейчас под политику ничего не попадает"
+		return &model.Post{
+			Message: message,
+		}
+	}
+	if len(report.Purge) > 0 {
+		message += fmt.Sprintf("Будут удалены опросы (%v):\n%s", len(report.Purge), formatRetained(report.Purge))
+	}
+	if len(report.Anonymize) > 0 {
+		message += fmt.Sprintf("Будут обезличены голоса в опросах (%v):\n%s", len(report.Anonymize), formatRetained(report.Anonymize))
+	}
+	return &model.Post{
+		Message: message,
+	}
+}
+
+func formatRetained(polls []*models.Poll) string {
+	message := ""
+	for _, poll := range polls {
+		message += fmt.Sprintf("\t```%s```: %s, завершен %s\n", poll.ID, poll.Question, formatDeadline(poll.ClosedAt))
+	}
+	return message
+}
+
+func days(d time.Duration) int {
+	return int(d / (24 * time.Hour))
+}
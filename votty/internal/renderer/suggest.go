@@ -0,0 +1,56 @@
+package renderer
+
+import (
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"votty/internal/models"
+	"votty/internal/service"
+)
+
+// Suggested confirms the option suggested by the user.
+func Suggested(suggested *service.Suggested) *model.Post {
+	if suggested.Pending {
+		return &model.Post{
+			Message: fmt.Sprintf("Вариант \"%s\" отправлен создателю опроса на одобрение", suggested.Suggestion.Option),
+		}
+	}
+	return &model.Post{
+		Message: fmt.Sprintf("Вариант \"%s\" добавлен в опрос ```%s```, за него можно голосовать командой ```/vote %s %v```",
+			suggested.Suggestion.Option, pollRef(suggested.Poll), pollRef(suggested.Poll), len(suggested.Poll.Options)),
+	}
+}
+
+// SuggestionDecided confirms the decision of the poll owner.
+func SuggestionDecided(suggested *service.Suggested, approved bool) *model.Post {
+	if !approved {
+		return &model.Post{
+			Message: fmt.Sprintf("Вариант \"%s\" отклонен", suggested.Suggestion.Option),
+		}
+	}
+	r := PollEdited(suggested.Poll)
+	r.Message = fmt.Sprintf("Вариант \"%s\" одобрен\n", suggested.Suggestion.Option) + r.Message
+	return r
+}
+
+// OptionSuggested is the direct message asking the poll owner to approve
+// the suggested option.
+func OptionSuggested(poll *models.Poll, suggestion *models.Suggestion, names Names) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("%s предлагает добавить вариант \"%s\" в опрос \"%s\" (```%s```)\nОдобрить: ```/suggest approve %s```, отклонить: ```/suggest reject %s```",
+			names.Of(suggestion.UserID), suggestion.Option, poll.Question, poll.ID, suggestion.ID, suggestion.ID),
+	}
+}
+
+// SuggestionAnswered is the direct message telling the user about the
+// decision on the suggested option.
+func SuggestionAnswered(poll *models.Poll, suggestion *models.Suggestion, approved bool) *model.Post {
+	if !approved {
+		return &model.Post{
+			Message: fmt.Sprintf("Создатель опроса \"%s\" (```%s```) отклонил твой вариант \"%s\"", poll.Question, poll.ID, suggestion.Option),
+		}
+	}
+	return &model.Post{
+		Message: fmt.Sprintf("Создатель опроса \"%s\" (```%s```) добавил твой вариант \"%s\", за него можно голосовать командой ```/vote %s %v```",
+			poll.Question, poll.ID, suggestion.Option, poll.ID, len(poll.Options)),
+	}
+}
@@ -0,0 +1,118 @@
+package renderer
+
+import (
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"strings"
+	"time"
+	"votty/internal/models"
+)
+
+func TemplateSaved(t *models.Template) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Шаблон ```%s``` сохранен. Создать по нему опрос можно командой ```/create --template %s```", t.Name, t.Name),
+	}
+}
+
+func TemplateDeleted(name string) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Шаблон ```%s``` был удален", name),
+	}
+}
+
+// Templates lists the user's own templates and the ones of the team.
+func Templates(own, team []*models.Template) *model.Post {
+	if len(own) == 0 && len(team) == 0 {
+		return &model.Post{
+			Message: "Шаблонов пока нет. Сохранить шаблон можно командой ```/template save Название | Вопрос? | Вариант1 | Вариант2```",
+		}
+	}
+
+	message := ""
+	if len(own) > 0 {
+		message += "Твои шаблоны:\n" + formatTemplates(own)
+	}
+	if len(team) > 0 {
+		message += "Шаблоны команды:\n" + formatTemplates(team)
+	}
+	return &model.Post{
+		Message: message,
+	}
+}
+
+func formatTemplates(templates []*models.Template) string {
+	message := ""
+	for _, t := range templates {
+		message += fmt.Sprintf("\t```%s```: %s | %s", t.Name, t.Question, strings.Join(t.Options, " | "))
+		if len(t.Flags) > 0 {
+			message += fmt.Sprintf(" (%s)", strings.Join(t.Flags, " "))
+		}
+		message += "\n"
+	}
+	return message
+}
+
+func ScheduleCreated(sc *models.Schedule) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Расписание ```%s``` создано. Следующий опрос по шаблону ```%s``` будет создан %s", sc.ID, sc.TemplateName, formatRun(sc)),
+	}
+}
+
+func SchedulePaused(sc *models.Schedule) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Расписание ```%s``` приостановлено", sc.ID),
+	}
+}
+
+func ScheduleResumed(sc *models.Schedule) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Расписание ```%s``` возобновлено, следующий опрос будет создан %s", sc.ID, formatRun(sc)),
+	}
+}
+
+func ScheduleDeleted(scheduleID string) *model.Post {
+	return &model.Post{
+		Message: fmt.Sprintf("Расписание ```%s``` было удалено", scheduleID),
+	}
+}
+
+// ScheduleFailed is the direct message telling the owner why the scheduled
+// poll has not been created.
+func ScheduleFailed(sc *models.Schedule, err error) *model.Post {
+	message := fmt.Sprintf("Не удалось создать опрос по расписанию ```%s``` (шаблон ```%s```)", sc.ID, sc.TemplateName)
+	if Expected(err) {
+		message += ": " + Error(CommandScheduleAdd, err).Message
+	}
+	return &model.Post{
+		Message: message,
+	}
+}
+
+func Schedules(schedules []*models.Schedule) *model.Post {
+	if len(schedules) == 0 {
+		return &model.Post{
+			Message: "Расписаний пока нет. Создать расписание можно командой ```/schedule add Шаблон | 0 10 * * 5```",
+		}
+	}
+
+	message := "Твои расписания:\n"
+	for _, sc := range schedules {
+		message += fmt.Sprintf("\t```%s```: шаблон ```%s```, ```%s``` (%s)", sc.ID, sc.TemplateName, sc.Cron, sc.Timezone)
+		if sc.Paused {
+			message += ", приостановлено\n"
+		} else {
+			message += fmt.Sprintf(", следующий запуск %s\n", formatRun(sc))
+		}
+	}
+	return &model.Post{
+		Message: message,
+	}
+}
+
+func formatRun(sc *models.Schedule) string {
+	run := time.Unix(sc.NextRun, 0)
+	if loc, err := time.LoadLocation(sc.Timezone); err == nil {
+		run = run.In(loc)
+	}
+	return run.Format("2006-01-02 15:04 MST")
+}
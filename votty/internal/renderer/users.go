@@ -0,0 +1,77 @@
+package renderer
+
+import (
+	"context"
+	"github.com/mattermost/mattermost/server/public/model"
+	"golang.org/x/exp/slog"
+	"time"
+	"votty/internal/cache"
+)
+
+const usernameTTL = 10 * time.Minute
+
+// Users resolves user IDs to the names shown in the messages. The names
+// are cached, so rendering does not hit the Mattermost API every time.
+type Users struct {
+	log    *slog.Logger
+	client *model.Client4
+	names  *cache.Cache[string, string]
+}
+
+func NewUsers(log *slog.Logger, client *model.Client4) *Users {
+	return &Users{log, client, cache.New[string, string](usernameTTL)}
+}
+
+// Names is a set of resolved user names keyed by user ID.
+type Names map[string]string
+
+// Of returns the @username of the user, or the ID itself when the user is
+// unknown or deactivated.
+func (n Names) Of(userID string) string {
+	if name, ok := n[userID]; ok && name != "" {
+		return name
+	}
+	return userID
+}
+
+// Names resolves the users in one request for all the IDs missing from the
+// cache. Deactivated users are cached without a name.
+func (u *Users) Names(ctx context.Context, userIDs ...string) Names {
+	names := make(Names, len(userIDs))
+
+	var missing []string
+	for _, id := range userIDs {
+		if name, ok := u.names.Get(id); ok {
+			names[id] = name
+			continue
+		}
+		if model.IsValidId(id) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return names
+	}
+
+	users, _, err := u.client.GetUsersByIds(ctx, missing)
+	if err != nil {
+		u.log.Warn("Failed to resolve usernames",
+			slog.Int("users", len(missing)),
+			slog.String("error", err.Error()),
+		)
+		return names
+	}
+
+	for _, id := range missing {
+		u.names.Set(id, "")
+	}
+	for _, user := range users {
+		name := ""
+		if user.DeleteAt == 0 {
+			name = "@" + user.Username
+		}
+		u.names.Set(user.Id, name)
+		names[user.Id] = name
+	}
+	return names
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/exp/slog"
+	"strings"
+	"votty/internal/models"
+	"votty/internal/storage/tarantool"
+)
+
+// OptionRemoved describes an option removed from a poll.
+type OptionRemoved struct {
+	Poll   *models.Poll
+	Option string
+	// Cleared is the number of deleted votes for the option.
+	Cleared int
+}
+
+// EditQuestion changes the question of the actor's active poll.
+func (p *Polls) EditQuestion(ctx context.Context, actor Actor, pollID, question string) (*models.Poll, error) {
+	poll, err := p.editable(actor, pollID)
+	if err != nil {
+		return nil, err
+	}
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, &ValidationError{"question", "вопрос не может быть пустым"}
+	}
+
+	if err = p.storage.UpdateQuestion(poll.ID, question); err != nil {
+		return nil, editError(err)
+	}
+	poll.Question = question
+
+	p.edited(ctx, actor, poll)
+	return poll, nil
+}
+
+// AddOption appends an option to the actor's active poll.
+func (p *Polls) AddOption(ctx context.Context, actor Actor, pollID, option string) (*models.Poll, error) {
+	poll, err := p.editable(actor, pollID)
+	if err != nil {
+		return nil, err
+	}
+	option = strings.TrimSpace(option)
+	if err = checkOption(poll, option, -1); err != nil {
+		return nil, err
+	}
+	if err = p.addOption(ctx, actor, poll, option); err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+// addOption appends the checked option to the poll unless the poll has
+// reached the maximum number of options.
+func (p *Polls) addOption(ctx context.Context, actor Actor, poll *models.Poll, option string) error {
+	if len(poll.Options) >= p.maxOptions {
+		return p.tooManyOptions()
+	}
+	if err := p.storage.AddOption(poll.ID, option, p.maxOptions); err != nil {
+		if errors.Is(err, tarantool.ErrTooManyOptions) {
+			return p.tooManyOptions()
+		}
+		return editError(err)
+	}
+	poll.Options = append(poll.Options, option)
+
+	p.edited(ctx, actor, poll)
+	return nil
+}
+
+func (p *Polls) tooManyOptions() error {
+	return &ValidationError{"options", fmt.Sprintf("в опросе уже максимальное число вариантов: %v", p.maxOptions)}
+}
+
+// RenameOption changes the text of the 1-based option of the actor's
+// active poll, the votes for it are kept.
+func (p *Polls) RenameOption(ctx context.Context, actor Actor, pollID string, option int, text string) (*models.Poll, error) {
+	poll, err := p.editable(actor, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if option < 1 || option > len(poll.Options) {
+		return nil, ErrInvalidOption
+	}
+	text = strings.TrimSpace(text)
+	if err = checkOption(poll, text, option-1); err != nil {
+		return nil, err
+	}
+
+	if err = p.storage.RenameOption(poll.ID, uint64(option-1), text); err != nil {
+		return nil, editError(err)
+	}
+	poll.Options[option-1] = text
+
+	p.edited(ctx, actor, poll)
+	return poll, nil
+}
+
+// RemoveOption removes the 1-based option of the actor's active poll. An
+// option with votes is removed only with clearVotes, then its voters are
+// told their votes have been deleted.
+func (p *Polls) RemoveOption(ctx context.Context, actor Actor, pollID string, option int, clearVotes bool) (*OptionRemoved, error) {
+	poll, err := p.editable(actor, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if option < 1 || option > len(poll.Options) {
+		return nil, ErrInvalidOption
+	}
+	if poll.Reactions != models.ReactionsOff {
+		return nil, &ValidationError{"options", "варианты быстрого опроса нельзя изменить"}
+	}
+
+	cleared, err := p.storage.RemoveOption(poll.ID, uint64(option-1), clearVotes)
+	if err != nil {
+		return nil, editError(err)
+	}
+	removed := &OptionRemoved{Poll: poll, Option: poll.Options[option-1], Cleared: len(cleared)}
+	poll.Options = append(poll.Options[:option-1:option-1], poll.Options[option:]...)
+
+	p.edited(ctx, actor, poll)
+	if len(cleared) > 0 {
+		p.notifier.VotesCleared(ctx, poll, removed.Option, realUsers(cleared))
+	}
+	return removed, nil
+}
+
+// editable returns the actor's poll if it can still be edited.
+func (p *Polls) editable(actor Actor, pollID string) (*models.Poll, error) {
+	poll, err := p.Get(pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.OwnerID != actor.UserID {
+		return nil, ErrNotOwner
+	}
+	if !poll.IsActive {
+		return nil, ErrPollClosed
+	}
+	return poll, nil
+}
+
+// edited announces the changes of the poll.
+func (p *Polls) edited(ctx context.Context, actor Actor, poll *models.Poll) {
+	p.log.Info("Edit",
+		slog.String("user_id", actor.UserID),
+		slog.String("pollID", poll.ID),
+	)
+	p.emit(models.EventPollUpdated, poll, nil)
+	p.notifier.PollUpdated(ctx, poll)
+}
+
+// checkOption validates the new text of the option with the index, -1 for
+// a new option. Options must stay distinct regardless of case and spaces.
+func checkOption(poll *models.Poll, option string, index int) error {
+	if poll.Reactions != models.ReactionsOff {
+		return &ValidationError{"options", "варианты быстрого опроса нельзя изменить"}
+	}
+	if option == "" {
+		return &ValidationError{"options", "вариант ответа не может быть пустым"}
+	}
+	for i, existing := range poll.Options {
+		if i != index && sameOption(existing, option) {
+			return duplicateOption()
+		}
+	}
+	return nil
+}
+
+// duplicateOption rejects an option the poll already has. The storage
+// checks it again, the options may have changed since the poll was read.
+func duplicateOption() error {
+	return &ValidationError{"options", "такой вариант ответа уже есть"}
+}
+
+// sameOption compares the options ignoring case and spaces.
+func sameOption(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
+
+// realUsers drops the random IDs of anonymized votes.
+func realUsers(userIDs []string) []string {
+	result := userIDs[:0]
+	for _, id := range userIDs {
+		if !strings.HasPrefix(id, "anonymous:") {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func editError(err error) error {
+	switch {
+	case errors.Is(err, tarantool.ErrNotFound):
+		return ErrPollNotFound
+	case errors.Is(err, tarantool.ErrPollClosed):
+		return ErrPollClosed
+	case errors.Is(err, tarantool.ErrInvalidChoice):
+		return ErrInvalidOption
+	case errors.Is(err, tarantool.ErrHasVotes):
+		return ErrOptionHasVotes
+	case errors.Is(err, tarantool.ErrLastOption):
+		return &ValidationError{"options", "нельзя удалить единственный вариант ответа"}
+	case errors.Is(err, tarantool.ErrDuplicate):
+		return duplicateOption()
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"votty/internal/models"
+)
+
+func TestSameOption(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Pizza", "Pizza", true},
+		{"Pizza", "pIZZA", true},
+		{"Big  pizza", " big pizza ", true},
+		{"Пицца", "ПИЦЦА", true},
+		{"Pizza", "Pizzas", false},
+		{"Big pizza", "Bigpizza", false},
+	}
+
+	for _, tt := range tests {
+		if got := sameOption(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameOption(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOption(t *testing.T) {
+	poll := &models.Poll{Options: []string{"Pizza", "Sushi"}}
+
+	tests := []struct {
+		name    string
+		poll    *models.Poll
+		option  string
+		index   int
+		wantErr bool
+	}{
+		{name: "new option", poll: poll, option: "Tacos", index: -1},
+		{name: "empty", poll: poll, option: "", index: -1, wantErr: true},
+		{name: "duplicate", poll: poll, option: "sushi", index: -1, wantErr: true},
+		{name: "renamed to itself", poll: poll, option: "PIZZA", index: 0},
+		{name: "renamed to another", poll: poll, option: "Sushi", index: 0, wantErr: true},
+		{name: "quick poll", poll: &models.Poll{Options: []string{"+1"}, Reactions: models.ReactionsAny}, option: "tada", index: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOption(tt.poll, tt.option, tt.index)
+			var validationErr *ValidationError
+			if tt.wantErr != errors.As(err, &validationErr) {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRealUsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		userIDs []string
+		want    []string
+	}{
+		{name: "none", userIDs: []string{}, want: []string{}},
+		{name: "real", userIDs: []string{"u1", "u2"}, want: []string{"u1", "u2"}},
+		{name: "anonymized", userIDs: []string{"anonymous:x", "u1", "anonymous:y", "u2"}, want: []string{"u1", "u2"}},
+		{name: "only anonymized", userIDs: []string{"anonymous:x"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := realUsers(tt.userIDs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("realUsers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
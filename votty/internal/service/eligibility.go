@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"net/http"
+	"time"
+	"votty/internal/cache"
+	"votty/internal/models"
+)
+
+// membershipTTL is how long a confirmed membership is trusted. Only
+// members are cached, so a user who has just joined the channel or the
+// group can vote right away.
+const membershipTTL = 5 * time.Minute
+
+// memberships caches the channel and group memberships confirmed by the
+// Mattermost API, so that voting does not hit the API on every command.
+type memberships = cache.Cache[string, struct{}]
+
+// resolveVoters replaces the usernames or the group name given in the
+// --voters flag with Mattermost IDs.
+func resolveVoters(ctx context.Context, client *model.Client4, poll *models.Poll) error {
+	switch poll.VotersMode {
+	case models.VotersUsers:
+		users, _, err := client.GetUsersByUsernames(ctx, poll.Voters)
+		if err != nil {
+			return err
+		}
+		ids := make(map[string]string, len(users))
+		for _, user := range users {
+			ids[user.Username] = user.Id
+		}
+
+		resolved := make([]string, 0, len(poll.Voters))
+		for _, username := range poll.Voters {
+			id, ok := ids[username]
+			if !ok {
+				return fmt.Errorf("пользователь @%s не найден", username)
+			}
+			resolved = append(resolved, id)
+		}
+		poll.Voters = resolved
+
+	case models.VotersGroup:
+		name := poll.Voters[0]
+		groups, _, err := client.GetGroups(ctx, model.GroupSearchOpts{Q: name})
+		if err != nil {
+			return err
+		}
+		for _, group := range groups {
+			if group.Name != nil && *group.Name == name {
+				poll.Voters = []string{group.Id}
+				return nil
+			}
+		}
+		return fmt.Errorf("группа %s не найдена", name)
+	}
+	return nil
+}
+
+// isEligible checks whether the user may vote in the poll and see its results.
+func (p *Polls) isEligible(ctx context.Context, poll *models.Poll, userID string) (bool, error) {
+	switch poll.VotersMode {
+	case models.VotersChannel:
+		key := "channel:" + poll.ChannelID + ":" + userID
+		if _, found := p.memberships.Get(key); found {
+			return true, nil
+		}
+
+		_, resp, err := p.client.GetChannelMember(ctx, poll.ChannelID, userID, "")
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		p.memberships.Set(key, struct{}{})
+		return true, nil
+
+	case models.VotersUsers:
+		for _, id := range poll.Voters {
+			if id == userID {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case models.VotersGroup:
+		key := "group:" + poll.Voters[0] + ":" + userID
+		if _, found := p.memberships.Get(key); found {
+			return true, nil
+		}
+
+		groups, _, err := p.client.GetGroupsByUserId(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		for _, group := range groups {
+			if group.Id == poll.Voters[0] {
+				p.memberships.Set(key, struct{}{})
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return true, nil
+}
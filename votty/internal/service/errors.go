@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrPollNotFound       = errors.New("poll not found")
+	ErrNotOwner           = errors.New("user is not the owner")
+	ErrNotAdmin           = errors.New("user is not a system admin")
+	ErrPollClosed         = errors.New("poll is closed")
+	ErrInvalidOption      = errors.New("option does not exist in the poll")
+	ErrNotEligible        = errors.New("user is not eligible for the poll")
+	ErrVoteLocked         = errors.New("vote cannot be changed in the poll")
+	ErrVoteNotFound       = errors.New("user has not voted in the poll")
+	ErrNotDeleted         = errors.New("poll is not in the trash")
+	ErrNotRemindable      = errors.New("reminders need an active poll restricted to channel members")
+	ErrRemindTooSoon      = errors.New("reminders of the poll have been sent recently")
+	ErrTemplateNotFound   = errors.New("template not found")
+	ErrScheduleNotFound   = errors.New("schedule not found")
+	ErrNoTeam             = errors.New("channel does not belong to a team")
+	ErrOptionHasVotes     = errors.New("option has votes")
+	ErrOptionsClosed      = errors.New("poll does not accept suggestions")
+	ErrSuggestionNotFound = errors.New("suggestion not found")
+)
+
+// ValidationError reports an invalid field of a command. Message is meant
+// for the user.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ResultsHiddenError reports that the results visibility mode of the poll
+// hides its results from the user.
+type ResultsHiddenError struct {
+	Visibility string
+}
+
+func (e *ResultsHiddenError) Error() string {
+	return fmt.Sprintf("results are hidden by the %s mode", e.Visibility)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"encoding/json"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"golang.org/x/exp/slog"
+	"time"
+	"votty/internal/models"
+)
+
+// emit puts a poll lifecycle event to the outbound webhook queue, nothing
+// is queued when no webhooks are configured. Failures are only logged,
+// webhooks must never break the command itself.
+func (p *Polls) emit(eventType string, poll *models.Poll, vote *models.Vote) {
+	if !p.webhooks {
+		return
+	}
+	log := p.log
+	if vote != nil && poll.Anonymous {
+		vote = &models.Vote{PollID: vote.PollID, Choice: vote.Choice}
+	}
+
+	id, err := gonanoid.New()
+	if err != nil {
+		log.Error("Failed to create the id for event",
+			slog.String("event", eventType),
+			slog.String("pollID", poll.ID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	event := &models.Event{
+		ID:        id,
+		Type:      eventType,
+		CreatedAt: time.Now().Unix(),
+		Poll:      poll,
+		Vote:      vote,
+	}
+
+	payload, err := json.Marshal(event)
+	if err == nil {
+		err = p.storage.EnqueueEvent(event, payload)
+	}
+	if err != nil {
+		log.Error("Failed to enqueue the webhook event",
+			slog.String("event", eventType),
+			slog.String("pollID", poll.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
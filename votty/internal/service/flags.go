@@ -0,0 +1,155 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"votty/internal/models"
+)
+
+// ParsePollFlags consumes the leading "--flag" and "--flag=value" tokens of
+// the /create question, applies them to the poll and returns the question
+// without them. Invalid flags are reported as a ValidationError.
+func ParsePollFlags(poll *models.Poll, input string) (string, error) {
+	rest := strings.TrimSpace(input)
+
+	for strings.HasPrefix(rest, "--") {
+		token, tail, _ := strings.Cut(rest, " ")
+		rest = strings.TrimSpace(tail)
+
+		name, value, _ := strings.Cut(strings.TrimPrefix(token, "--"), "=")
+		if err := ApplyPollFlag(poll, name, value); err != nil {
+			return "", err
+		}
+	}
+
+	if poll.RemindBefore > 0 && poll.ClosesAt == 0 {
+		return "", &ValidationError{"remind", "флаг --remind можно использовать только вместе с --deadline"}
+	}
+
+	return rest, nil
+}
+
+// ApplyPollFlag applies a single /create flag given without the dashes, the
+// value of a switch like --locked is empty. The ValidationError it returns
+// names the flag in Field.
+func ApplyPollFlag(poll *models.Poll, name, value string) error {
+	switch name {
+	case "locked":
+		if value != "" {
+			return &ValidationError{name, "флаг --locked не принимает значение"}
+		}
+		poll.LockedVotes = true
+	case "anonymous":
+		if value != "" {
+			return &ValidationError{name, "флаг --anonymous не принимает значение"}
+		}
+		poll.Anonymous = true
+	case "results":
+		switch value {
+		case models.ResultsAlways, models.ResultsAfterVote, models.ResultsAfterClose:
+			poll.ResultsVisibility = value
+		default:
+			return &ValidationError{name, fmt.Sprintf("флаг --results принимает одно из значений: %s, %s, %s",
+				models.ResultsAlways, models.ResultsAfterVote, models.ResultsAfterClose)}
+		}
+	case "quorum":
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || n == 0 {
+			return &ValidationError{name, "флаг --quorum должен быть положительным числом участников"}
+		}
+		poll.Quorum = n
+	case "threshold":
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || n == 0 {
+			return &ValidationError{name, "флаг --threshold должен быть положительным числом голосов"}
+		}
+		poll.Threshold = n
+	case "voters":
+		switch {
+		case value == "channel":
+			poll.VotersMode = models.VotersChannel
+		case strings.HasPrefix(value, "group:") && len(value) > len("group:"):
+			poll.VotersMode = models.VotersGroup
+			poll.Voters = []string{strings.TrimPrefix(value, "group:")}
+		case strings.HasPrefix(value, "@"):
+			poll.VotersMode = models.VotersUsers
+			poll.Voters = nil
+			for _, username := range strings.Split(value, ",") {
+				username = strings.TrimPrefix(strings.TrimSpace(username), "@")
+				if username != "" {
+					poll.Voters = append(poll.Voters, username)
+				}
+			}
+			if len(poll.Voters) == 0 {
+				return &ValidationError{name, "в флаге --voters не указаны пользователи"}
+			}
+		default:
+			return &ValidationError{name, "флаг --voters принимает значение channel, group:имя_группы или список @пользователей через запятую"}
+		}
+	case "single":
+		if value != "" {
+			return &ValidationError{name, "флаг --single не принимает значение"}
+		}
+		if poll.Reactions == models.ReactionsOff {
+			return &ValidationError{name, "флаг --single доступен только в команде /quick"}
+		}
+		poll.Reactions = models.ReactionsSingle
+	case "open":
+		switch value {
+		case "", models.OptionsOpen:
+			poll.OpenOptions = models.OptionsOpen
+		case models.OptionsApproval:
+			poll.OpenOptions = models.OptionsApproval
+		default:
+			return &ValidationError{name, "флаг --open не принимает значение или принимает ```--open=approval```, чтобы предложенные варианты добавлялись после одобрения создателя"}
+		}
+	case "deadline":
+		closesAt, err := parseDeadline(value)
+		if err != nil {
+			return &ValidationError{name, "флаг --deadline принимает длительность (```--deadline=24h```, ```--deadline=3d```) или время в UTC (```--deadline=2025-01-31T18:00```)"}
+		}
+		poll.ClosesAt = closesAt.Unix()
+	case "remind":
+		d, err := ParseDuration(value)
+		if err != nil || d <= 0 {
+			return &ValidationError{name, "флаг --remind принимает длительность, например ```--remind=2h```"}
+		}
+		poll.RemindBefore = int64(d.Seconds())
+	default:
+		return &ValidationError{name, fmt.Sprintf("неизвестный флаг --%s", name)}
+	}
+	return nil
+}
+
+// parseDeadline accepts either a duration from now or an absolute UTC time.
+func parseDeadline(value string) (time.Time, error) {
+	if d, err := ParseDuration(value); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("deadline must be in the future")
+		}
+		return time.Now().Add(d), nil
+	}
+
+	deadline, err := time.Parse("2006-01-02T15:04", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !deadline.After(time.Now()) {
+		return time.Time{}, fmt.Errorf("deadline must be in the future")
+	}
+	return deadline, nil
+}
+
+// ParseDuration extends time.ParseDuration with whole days, e.g. "3d".
+func ParseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+	"votty/internal/models"
+)
+
+func TestParsePollFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		question string
+		want     models.Poll
+		field    string
+	}{
+		{
+			name:     "no flags",
+			input:    "  Where do we go?  ",
+			question: "Where do we go?",
+		},
+		{
+			name:     "switches",
+			input:    "--locked --anonymous Where?",
+			question: "Where?",
+			want:     models.Poll{LockedVotes: true, Anonymous: true},
+		},
+		{
+			name:     "values",
+			input:    "--results=after-close --quorum=3 --threshold=2 Where?",
+			question: "Where?",
+			want:     models.Poll{ResultsVisibility: models.ResultsAfterClose, Quorum: 3, Threshold: 2},
+		},
+		{
+			name:     "flags after the question are kept",
+			input:    "Where? --locked",
+			question: "Where? --locked",
+		},
+		{
+			name:     "voters channel",
+			input:    "--voters=channel Where?",
+			question: "Where?",
+			want:     models.Poll{VotersMode: models.VotersChannel},
+		},
+		{
+			name:     "voters group",
+			input:    "--voters=group:devs Where?",
+			question: "Where?",
+			want:     models.Poll{VotersMode: models.VotersGroup, Voters: []string{"devs"}},
+		},
+		{
+			name:     "voters users",
+			input:    "--voters=@alice,,@bob Where?",
+			question: "Where?",
+			want:     models.Poll{VotersMode: models.VotersUsers, Voters: []string{"alice", "bob"}},
+		},
+		{
+			name:     "open",
+			input:    "--open Where?",
+			question: "Where?",
+			want:     models.Poll{OpenOptions: models.OptionsOpen},
+		},
+		{
+			name:     "open with approval",
+			input:    "--open=approval Where?",
+			question: "Where?",
+			want:     models.Poll{OpenOptions: models.OptionsApproval},
+		},
+		{name: "unknown flag", input: "--secret Where?", field: "secret"},
+		{name: "switch with a value", input: "--locked=yes Where?", field: "locked"},
+		{name: "invalid results", input: "--results=never Where?", field: "results"},
+		{name: "zero quorum", input: "--quorum=0 Where?", field: "quorum"},
+		{name: "negative threshold", input: "--threshold=-1 Where?", field: "threshold"},
+		{name: "no voters", input: "--voters=@ Where?", field: "voters"},
+		{name: "empty group", input: "--voters=group: Where?", field: "voters"},
+		{name: "invalid open", input: "--open=later Where?", field: "open"},
+		{name: "single outside quick polls", input: "--single Where?", field: "single"},
+		{name: "past deadline", input: "--deadline=2000-01-01T00:00 Where?", field: "deadline"},
+		{name: "remind without deadline", input: "--remind=1h Where?", field: "remind"},
+		{name: "zero remind", input: "--deadline=1d --remind=0s Where?", field: "remind"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var poll models.Poll
+			question, err := ParsePollFlags(&poll, tt.input)
+
+			if tt.field != "" {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("err = %v, want a ValidationError", err)
+				}
+				if validationErr.Field != tt.field {
+					t.Errorf("Field = %q, want %q", validationErr.Field, tt.field)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if question != tt.question {
+				t.Errorf("question = %q, want %q", question, tt.question)
+			}
+			if !reflect.DeepEqual(poll, tt.want) {
+				t.Errorf("poll = %+v, want %+v", poll, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePollFlagsDeadline(t *testing.T) {
+	var poll models.Poll
+	before := time.Now()
+	question, err := ParsePollFlags(&poll, "--deadline=2d --remind=3h Where?")
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if question != "Where?" {
+		t.Errorf("question = %q", question)
+	}
+
+	closesAt := time.Unix(poll.ClosesAt, 0)
+	if want := before.Add(48 * time.Hour).Truncate(time.Second); closesAt.Before(want) || closesAt.After(want.Add(time.Minute)) {
+		t.Errorf("ClosesAt = %v, want about %v", closesAt, want)
+	}
+	if poll.RemindBefore != 3*60*60 {
+		t.Errorf("RemindBefore = %d, want %d", poll.RemindBefore, 3*60*60)
+	}
+}
+
+func TestApplyPollFlagSingle(t *testing.T) {
+	poll := models.Poll{Reactions: models.ReactionsAny}
+	if err := ApplyPollFlag(&poll, "single", ""); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if poll.Reactions != models.ReactionsSingle {
+		t.Errorf("Reactions = %q, want %q", poll.Reactions, models.ReactionsSingle)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "90m", want: 90 * time.Minute},
+		{value: "24h", want: 24 * time.Hour},
+		{value: "3d", want: 72 * time.Hour},
+		{value: "0d", want: 0},
+		{value: "1.5d", wantErr: true},
+		{value: "d", wantErr: true},
+		{value: "week", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDuration(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDeadline(t *testing.T) {
+	future := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Minute)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "absolute", value: future.Format("2006-01-02T15:04"), want: future},
+		{name: "past", value: "2000-01-01T00:00", wantErr: true},
+		{name: "zero duration", value: "0h", wantErr: true},
+		{name: "negative duration", value: "-1h", wantErr: true},
+		{name: "with seconds", value: future.Format("2006-01-02T15:04:05"), wantErr: true},
+		{name: "garbage", value: "tomorrow", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDeadline(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseDeadline(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"votty/internal/models"
+)
+
+// Close reasons of a poll closed automatically.
+const (
+	CloseQuorum    = "quorum"
+	CloseThreshold = "threshold"
+	CloseDeadline  = "deadline"
+)
+
+// CloseReason explains why a poll has been closed automatically.
+type CloseReason struct {
+	Kind string
+	// Option is the 1-based option that reached the threshold.
+	Option int
+}
+
+// Notifier delivers the messages the service produces on its own, outside
+// of a reply to a command.
+type Notifier interface {
+	// PollCreated announces the poll in its channel and returns the ID of
+	// the announcement post, empty if it has not been posted.
+	PollCreated(ctx context.Context, poll *models.Poll) string
+	// PollClosed posts the outcome of an automatically closed poll.
+	PollClosed(ctx context.Context, results *Results, reason CloseReason)
+	// Remind sends the users a direct message about the poll.
+	Remind(ctx context.Context, poll *models.Poll, userIDs []string)
+	// PollUpdated refreshes the announcement of an edited poll.
+	PollUpdated(ctx context.Context, poll *models.Poll)
+	// VotesCleared tells the users their votes for the removed option
+	// have been deleted.
+	VotesCleared(ctx context.Context, poll *models.Poll, option string, userIDs []string)
+	// OptionSuggested asks the poll owner to approve the suggested option.
+	OptionSuggested(ctx context.Context, poll *models.Poll, suggestion *models.Suggestion)
+	// SuggestionDecided tells the user whether the owner has approved the
+	// suggested option.
+	SuggestionDecided(ctx context.Context, poll *models.Poll, suggestion *models.Suggestion, approved bool)
+	// ScheduleFailed tells the schedule owner why the scheduled poll has not
+	// been created.
+	ScheduleFailed(ctx context.Context, sc *models.Schedule, err error)
+}
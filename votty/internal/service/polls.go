@@ -0,0 +1,631 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/mattermost/mattermost/server/public/model"
+	"golang.org/x/exp/slog"
+	"strconv"
+	"strings"
+	"time"
+	"votty/internal/cache"
+	"votty/internal/models"
+	"votty/internal/storage/tarantool"
+)
+
+// Actor identifies who runs a command and from which channel.
+type Actor struct {
+	UserID    string
+	ChannelID string
+	// RootID is the root post of the thread the command was sent in.
+	RootID string
+}
+
+// VoteResult describes an accepted vote.
+type VoteResult struct {
+	Poll *models.Poll
+	Vote *models.Vote
+	// Changed is set when the vote replaced an earlier one of the user.
+	Changed bool
+}
+
+// Results are the vote counts of a poll, indexed like its options.
+type Results struct {
+	Poll   *models.Poll `json:"poll"`
+	Votes  []int        `json:"votes"`
+	Voters int          `json:"voters"`
+	// QuorumMet is nil for polls without a quorum.
+	QuorumMet *bool `json:"quorum_met,omitempty"`
+}
+
+// TrashedPoll is a deleted poll that can still be restored.
+type TrashedPoll struct {
+	Poll *models.Poll
+	// PurgeAt is when the poll is deleted for good in unix seconds.
+	PurgeAt int64
+}
+
+// Polls is the domain API over polls and votes. It works with typed
+// commands, models and errors and knows nothing about chat posts.
+type Polls struct {
+	log      *slog.Logger
+	storage  *tarantool.Storage
+	client   *model.Client4
+	notifier Notifier
+	botID    string
+	// deleteGrace is how long deleted polls stay in the trash.
+	deleteGrace time.Duration
+	// maxOptions limits the options added to a poll after its creation.
+	maxOptions int
+	// webhooks is set when the events are delivered to webhooks.
+	webhooks bool
+	// memberships caches the confirmed channel and group memberships.
+	memberships *memberships
+}
+
+func NewPolls(log *slog.Logger, storage *tarantool.Storage, client *model.Client4, notifier Notifier, botID string, deleteGrace time.Duration, maxOptions int, webhooks bool) *Polls {
+	return &Polls{log, storage, client, notifier, botID, deleteGrace, maxOptions, webhooks,
+		cache.New[string, struct{}](membershipTTL)}
+}
+
+// Create validates the poll, resolves its voters and stores it as a new
+// active poll owned by the actor.
+func (p *Polls) Create(ctx context.Context, actor Actor, poll *models.Poll) (*models.Poll, error) {
+	poll.OwnerID = actor.UserID
+	if poll.ChannelID == "" {
+		poll.ChannelID = actor.ChannelID
+	}
+	poll.IsActive = true
+	poll.Reminded = false
+
+	poll.Question = strings.TrimSpace(poll.Question)
+	for i := range poll.Options {
+		poll.Options[i] = strings.TrimSpace(poll.Options[i])
+	}
+
+	if err := validatePoll(poll); err != nil {
+		return nil, err
+	}
+	if err := resolveVoters(ctx, p.client, poll); err != nil {
+		return nil, &ValidationError{"voters", err.Error()}
+	}
+
+	id, err := gonanoid.New(10)
+	if err != nil {
+		return nil, err
+	}
+	poll.ID = id
+
+	if poll.ChannelID != "" {
+		if poll.Number, err = p.storage.NextPollNumber(poll.ChannelID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = p.storage.CreatePoll(poll); err != nil {
+		return nil, err
+	}
+
+	p.log.Info("Create",
+		slog.String("user_id", actor.UserID),
+		slog.String("pollID", poll.ID),
+	)
+	p.emit(models.EventPollCreated, poll, nil)
+
+	return poll, nil
+}
+
+// Announce posts the description of a new poll to its channel.
+func (p *Polls) Announce(ctx context.Context, poll *models.Poll) {
+	if postID := p.notifier.PollCreated(ctx, poll); postID != "" {
+		p.AttachPost(poll.ID, postID)
+	}
+}
+
+// AttachPost records the post that announced the poll, so that commands
+// sent in its thread refer to the poll.
+func (p *Polls) AttachPost(pollID, postID string) {
+	if err := p.storage.SetPollPost(pollID, postID); err != nil {
+		p.log.Error("Failed to attach the post to the poll",
+			slog.String("pollID", pollID),
+			slog.String("postID", postID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// Resolve maps a poll reference of a chat command to the poll ID. The
+// reference is the poll ID, the channel-local number like #3, last for
+// the latest poll of the channel, or empty in the thread of a poll post.
+func (p *Polls) Resolve(actor Actor, ref string) (string, error) {
+	var (
+		poll *models.Poll
+		err  error
+	)
+	switch {
+	case ref == "" && actor.RootID == "":
+		return "", ErrPollNotFound
+	case ref == "":
+		poll, err = p.storage.PollByPost(actor.RootID)
+	case ref == "last":
+		poll, err = p.storage.LastPoll(actor.ChannelID)
+	case strings.HasPrefix(ref, "#"):
+		number, parseErr := strconv.ParseUint(ref[1:], 10, 64)
+		if parseErr != nil || number == 0 {
+			return "", ErrPollNotFound
+		}
+		poll, err = p.storage.PollByNumber(actor.ChannelID, number)
+	default:
+		return ref, nil
+	}
+
+	if errors.Is(err, tarantool.ErrNotFound) {
+		return "", ErrPollNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return poll.ID, nil
+}
+
+// Get returns the poll, the polls in the trash are not found.
+func (p *Polls) Get(pollID string) (*models.Poll, error) {
+	poll, err := p.storage.GetPoll(pollID)
+	if errors.Is(err, tarantool.ErrNotFound) {
+		return nil, ErrPollNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if poll.DeletedAt > 0 {
+		return nil, ErrPollNotFound
+	}
+	return poll, nil
+}
+
+func (p *Polls) ListByOwner(ownerID string) ([]*models.Poll, error) {
+	polls, err := p.storage.ListPollsByOwner(ownerID)
+	return withoutDeleted(polls), err
+}
+
+func (p *Polls) ListByChannel(channelID string) ([]*models.Poll, error) {
+	polls, err := p.storage.ListPollsByChannel(channelID)
+	return withoutDeleted(polls), err
+}
+
+func withoutDeleted(polls []*models.Poll) []*models.Poll {
+	result := polls[:0]
+	for _, poll := range polls {
+		if poll.DeletedAt == 0 {
+			result = append(result, poll)
+		}
+	}
+	return result
+}
+
+// Vote casts or changes the actor's vote, choice is 1-based. The poll is
+// checked once more by Tarantool in the same transaction that stores the
+// vote. After the vote the quorum and threshold rules may close the poll.
+func (p *Polls) Vote(ctx context.Context, actor Actor, pollID string, choice int) (*VoteResult, error) {
+	poll, err := p.Get(pollID)
+	if err != nil {
+		return nil, err
+	}
+	if !poll.IsActive {
+		return nil, ErrPollClosed
+	}
+
+	eligible, err := p.isEligible(ctx, poll, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !eligible {
+		return nil, ErrNotEligible
+	}
+
+	if choice < 1 || choice > len(poll.Options) {
+		return nil, ErrInvalidOption
+	}
+
+	vote := &models.Vote{PollID: poll.ID, UserID: actor.UserID, Choice: uint64(choice - 1)}
+	result := &VoteResult{Poll: poll, Vote: vote}
+
+	result.Changed, err = p.storage.CastVote(vote.PollID, vote.UserID, vote.Choice)
+	switch {
+	case errors.Is(err, tarantool.ErrNotFound):
+		return nil, ErrPollNotFound
+	case errors.Is(err, tarantool.ErrPollClosed):
+		return nil, ErrPollClosed
+	case errors.Is(err, tarantool.ErrInvalidChoice):
+		return nil, ErrInvalidOption
+	case errors.Is(err, tarantool.ErrAlreadyExists):
+		return nil, ErrVoteLocked
+	case err != nil:
+		return nil, err
+	}
+
+	if result.Changed {
+		p.emit(models.EventVoteChanged, poll, vote)
+	} else {
+		p.emit(models.EventVoteCast, poll, vote)
+	}
+
+	// Polls created before the channel was recorded announce their outcome
+	// where the vote came from.
+	if poll.ChannelID == "" {
+		poll.ChannelID = actor.ChannelID
+	}
+	p.autoClose(ctx, poll)
+
+	return result, nil
+}
+
+// Retract removes the actor's vote from an active poll.
+func (p *Polls) Retract(actor Actor, pollID string) error {
+	err := p.storage.RetractVote(pollID, actor.UserID)
+	switch {
+	case errors.Is(err, tarantool.ErrNotFound):
+		return ErrPollNotFound
+	case errors.Is(err, tarantool.ErrPollClosed):
+		return ErrPollClosed
+	case errors.Is(err, tarantool.ErrAlreadyExists):
+		return ErrVoteLocked
+	case errors.Is(err, tarantool.ErrNoVote):
+		return ErrVoteNotFound
+	}
+	return err
+}
+
+// Recount rebuilds the tallies of the actor's poll from the raw votes and
+// reports whether they were wrong.
+func (p *Polls) Recount(actor Actor, pollID string) (bool, error) {
+	poll, err := p.Get(pollID)
+	if err != nil {
+		return false, err
+	}
+	if poll.OwnerID != actor.UserID {
+		return false, ErrNotOwner
+	}
+
+	fixed, err := p.storage.RebuildTallies(poll.ID)
+	if err != nil {
+		return false, err
+	}
+	if len(fixed) > 0 {
+		p.log.Warn("poll tallies have been fixed",
+			slog.String("user_id", actor.UserID),
+			slog.String("pollID", poll.ID),
+		)
+	}
+	return len(fixed) > 0, nil
+}
+
+// Close ends the actor's poll, the results stay available.
+func (p *Polls) Close(actor Actor, pollID string) (*models.Poll, error) {
+	poll, err := p.Get(pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.OwnerID != actor.UserID {
+		return nil, ErrNotOwner
+	}
+
+	closed, err := p.storage.EndPoll(poll.ID)
+	if errors.Is(err, tarantool.ErrNotFound) {
+		return nil, ErrPollNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !closed {
+		return nil, ErrPollClosed
+	}
+	poll.IsActive = false
+	p.emit(models.EventPollClosed, poll, nil)
+
+	p.log.Info("poll has been closed",
+		slog.String("user_id", actor.UserID),
+		slog.String("pollID", poll.ID),
+	)
+	return poll, nil
+}
+
+// Delete moves the actor's poll to the trash. It can be restored with
+// Undelete until the grace period passes and PurgeDeleted removes it.
+func (p *Polls) Delete(actor Actor, pollID string) (*TrashedPoll, error) {
+	poll, err := p.Get(pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.OwnerID != actor.UserID {
+		return nil, ErrNotOwner
+	}
+
+	poll.DeletedAt = time.Now().Unix()
+	if err = p.storage.SoftDeletePoll(poll.ID, poll.DeletedAt); err != nil {
+		return nil, err
+	}
+
+	p.log.Info("poll has been moved to the trash",
+		slog.String("user_id", actor.UserID),
+		slog.String("pollID", poll.ID),
+	)
+	return p.trashed(poll), nil
+}
+
+// Undelete restores the actor's poll from the trash.
+func (p *Polls) Undelete(actor Actor, pollID string) (*models.Poll, error) {
+	poll, err := p.storage.GetPoll(pollID)
+	if errors.Is(err, tarantool.ErrNotFound) {
+		return nil, ErrPollNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if poll.OwnerID != actor.UserID {
+		return nil, ErrNotOwner
+	}
+	if poll.DeletedAt == 0 {
+		return nil, ErrNotDeleted
+	}
+	// The poll waits for the purge job, but the grace period is over.
+	if p.trashed(poll).PurgeAt <= time.Now().Unix() {
+		return nil, ErrPollNotFound
+	}
+
+	if err = p.storage.RestorePoll(poll.ID); err != nil {
+		return nil, err
+	}
+	poll.DeletedAt = 0
+
+	p.log.Info("poll has been restored",
+		slog.String("user_id", actor.UserID),
+		slog.String("pollID", poll.ID),
+	)
+	return poll, nil
+}
+
+// Trash lists the actor's deleted polls that can still be restored.
+func (p *Polls) Trash(actor Actor) ([]*TrashedPoll, error) {
+	polls, err := p.storage.ListPollsByOwner(actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	var trash []*TrashedPoll
+	for _, poll := range polls {
+		if poll.DeletedAt == 0 {
+			continue
+		}
+		if t := p.trashed(poll); t.PurgeAt > now {
+			trash = append(trash, t)
+		}
+	}
+	return trash, nil
+}
+
+func (p *Polls) trashed(poll *models.Poll) *TrashedPoll {
+	return &TrashedPoll{Poll: poll, PurgeAt: poll.DeletedAt + int64(p.deleteGrace.Seconds())}
+}
+
+// purgeBatch bounds the polls purged from the trash by a single run, the
+// rest is left to the next runs.
+const purgeBatch = 500
+
+// PurgeDeleted removes the polls whose grace period in the trash is over,
+// together with their votes.
+func (p *Polls) PurgeDeleted() {
+	polls, err := p.storage.DeletedPolls(time.Now().Add(-p.deleteGrace).Unix(), purgeBatch)
+	if err != nil {
+		p.log.Error("Failed to select deleted polls",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	for _, poll := range polls {
+		err = p.storage.DeletePoll(poll.ID)
+		if err != nil && !errors.Is(err, tarantool.ErrNotFound) {
+			p.log.Error("Failed to purge the deleted poll",
+				slog.String("pollID", poll.ID),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+
+		p.log.Info("deleted poll has been purged",
+			slog.String("pollID", poll.ID),
+		)
+	}
+}
+
+// PurgeOrphans deletes the votes left behind by polls deleted before the
+// deletion cascaded to them.
+func (p *Polls) PurgeOrphans() {
+	purged, err := p.storage.PurgeOrphans()
+	if err != nil {
+		p.log.Error("Failed to purge orphaned votes",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if purged > 0 {
+		p.log.Info("orphaned votes have been purged",
+			slog.Int("votes", purged),
+		)
+	}
+}
+
+// Results returns the vote counts if the actor may see them. The owner
+// may force the results of a poll that hides them.
+func (p *Polls) Results(ctx context.Context, actor Actor, pollID string, force bool) (*Results, error) {
+	poll, err := p.Get(pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	if force && poll.OwnerID != actor.UserID {
+		return nil, ErrNotOwner
+	}
+	if err = p.checkVisible(ctx, actor, poll); err != nil {
+		return nil, err
+	}
+
+	if !force {
+		if err = p.checkResultsVisible(poll, actor.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.results(poll)
+}
+
+// View returns the poll if the actor may see it, like for Results.
+func (p *Polls) View(ctx context.Context, actor Actor, pollID string) (*models.Poll, error) {
+	poll, err := p.Get(pollID)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.checkVisible(ctx, actor, poll); err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+// Visible keeps the polls the actor may see.
+func (p *Polls) Visible(ctx context.Context, actor Actor, polls []*models.Poll) ([]*models.Poll, error) {
+	visible := make([]*models.Poll, 0, len(polls))
+	for _, poll := range polls {
+		err := p.checkVisible(ctx, actor, poll)
+		if errors.Is(err, ErrNotEligible) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		visible = append(visible, poll)
+	}
+	return visible, nil
+}
+
+// checkVisible lets the owner and the users eligible to vote see the poll.
+func (p *Polls) checkVisible(ctx context.Context, actor Actor, poll *models.Poll) error {
+	if poll.OwnerID == actor.UserID {
+		return nil
+	}
+	eligible, err := p.isEligible(ctx, poll, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if !eligible {
+		return ErrNotEligible
+	}
+	return nil
+}
+
+func (p *Polls) results(poll *models.Poll) (*Results, error) {
+	votes, err := p.storage.PollResults(poll.ID, len(poll.Options))
+	if err != nil {
+		return nil, err
+	}
+
+	results := &Results{Poll: poll, Votes: votes, Voters: totalVotes(votes)}
+	if poll.Quorum > 0 {
+		met := uint64(results.Voters) >= poll.Quorum
+		results.QuorumMet = &met
+	}
+	return results, nil
+}
+
+// checkResultsVisible applies the results visibility mode of the poll to
+// the user.
+func (p *Polls) checkResultsVisible(poll *models.Poll, userID string) error {
+	if !poll.IsActive {
+		return nil
+	}
+
+	switch poll.ResultsVisibility {
+	case models.ResultsAfterClose:
+		return &ResultsHiddenError{poll.ResultsVisibility}
+	case models.ResultsAfterVote:
+		_, err := p.storage.SelectVotes(poll.ID, userID)
+		if errors.Is(err, tarantool.ErrNotFound) {
+			return &ResultsHiddenError{poll.ResultsVisibility}
+		}
+		return err
+	}
+	return nil
+}
+
+// validatePoll checks the poll settings with the same rules the /create
+// flags follow.
+func validatePoll(poll *models.Poll) error {
+	if poll.Question == "" {
+		return &ValidationError{"question", "вопрос не может быть пустым"}
+	}
+	if len(poll.Options) == 0 {
+		return &ValidationError{"options", "нужен хотя бы один вариант ответа"}
+	}
+	for _, option := range poll.Options {
+		if option == "" {
+			return &ValidationError{"options", "вариант ответа не может быть пустым"}
+		}
+	}
+
+	switch poll.ResultsVisibility {
+	case "", models.ResultsAlways, models.ResultsAfterVote, models.ResultsAfterClose:
+	default:
+		return &ValidationError{"results_visibility", fmt.Sprintf("допустимые значения: %s, %s, %s",
+			models.ResultsAlways, models.ResultsAfterVote, models.ResultsAfterClose)}
+	}
+
+	switch poll.Reactions {
+	case models.ReactionsOff:
+	case models.ReactionsAny, models.ReactionsSingle:
+		if err := validateEmojis(poll.Options); err != nil {
+			return err
+		}
+	default:
+		return &ValidationError{"reactions", fmt.Sprintf("допустимые значения: %s, %s",
+			models.ReactionsAny, models.ReactionsSingle)}
+	}
+
+	switch poll.OpenOptions {
+	case models.OptionsClosed:
+	case models.OptionsOpen, models.OptionsApproval:
+		if poll.Reactions != models.ReactionsOff {
+			return &ValidationError{"open", "в быстром опросе нельзя предлагать варианты"}
+		}
+	default:
+		return &ValidationError{"open_options", fmt.Sprintf("допустимые значения: %s, %s",
+			models.OptionsOpen, models.OptionsApproval)}
+	}
+
+	switch poll.VotersMode {
+	case models.VotersAll, models.VotersChannel:
+	case models.VotersUsers, models.VotersGroup:
+		if len(poll.Voters) == 0 {
+			return &ValidationError{"voters", "не указаны пользователи или группа"}
+		}
+	default:
+		return &ValidationError{"voters_mode", fmt.Sprintf("допустимые значения: %s, %s, %s",
+			models.VotersChannel, models.VotersUsers, models.VotersGroup)}
+	}
+	if poll.VotersMode == models.VotersChannel && poll.ChannelID == "" {
+		return &ValidationError{"channel_id", "нужен канал опроса"}
+	}
+
+	if poll.ClosesAt != 0 && poll.ClosesAt <= time.Now().Unix() {
+		return &ValidationError{"closes_at", "срок окончания должен быть в будущем"}
+	}
+	if poll.RemindBefore < 0 {
+		return &ValidationError{"remind_before", "должно быть положительным"}
+	}
+	if poll.RemindBefore > 0 && poll.ClosesAt == 0 {
+		return &ValidationError{"remind_before", "напоминание можно задать только вместе со сроком окончания"}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+//go:build integration
+
+package service
+
+import (
+	"context"
+	"errors"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"testing"
+	"votty/internal/models"
+)
+
+func TestResolveStored(t *testing.T) {
+	ctx := context.Background()
+	polls, storage, _ := testPolls(t, 20)
+	owner := Actor{UserID: "owner", ChannelID: gonanoid.Must(10)}
+
+	var created []*models.Poll
+	for i := 0; i < 2; i++ {
+		poll, err := polls.Create(ctx, owner, &models.Poll{Question: "Where?", Options: []string{"Here", "There"}})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		t.Cleanup(func() { storage.DeletePoll(poll.ID) })
+		created = append(created, poll)
+	}
+	if err := storage.SetPollPost(created[0].ID, "post"+created[0].ID); err != nil {
+		t.Fatalf("SetPollPost: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		actor   Actor
+		ref     string
+		want    string
+		wantErr error
+	}{
+		{name: "number", actor: owner, ref: "#1", want: created[0].ID},
+		{name: "last", actor: owner, ref: "last", want: created[1].ID},
+		{name: "thread", actor: Actor{UserID: "u1", RootID: "post" + created[0].ID}, want: created[0].ID},
+		{name: "number of another channel", actor: Actor{ChannelID: gonanoid.Must(10)}, ref: "#1", wantErr: ErrPollNotFound},
+		{name: "unknown number", actor: owner, ref: "#3", wantErr: ErrPollNotFound},
+		{name: "last of an empty channel", actor: Actor{ChannelID: gonanoid.Must(10)}, ref: "last", wantErr: ErrPollNotFound},
+		{name: "thread without poll", actor: Actor{RootID: "missing"}, wantErr: ErrPollNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := polls.Resolve(tt.actor, tt.ref)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"votty/internal/models"
+)
+
+// TestResolve covers the references resolved without the storage, the
+// lookups by post, number and last are covered by the integration tests.
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   Actor
+		ref     string
+		want    string
+		wantErr error
+	}{
+		{name: "poll ID", ref: "V1StGXR8_Z", want: "V1StGXR8_Z"},
+		{name: "poll ID in a thread", actor: Actor{RootID: "post"}, ref: "V1StGXR8_Z", want: "V1StGXR8_Z"},
+		{name: "empty outside a thread", ref: "", wantErr: ErrPollNotFound},
+		{name: "zero number", ref: "#0", wantErr: ErrPollNotFound},
+		{name: "bare hash", ref: "#", wantErr: ErrPollNotFound},
+		{name: "not a number", ref: "#abc", wantErr: ErrPollNotFound},
+		{name: "negative number", ref: "#-1", wantErr: ErrPollNotFound},
+		{name: "overflow", ref: "#99999999999999999999", wantErr: ErrPollNotFound},
+	}
+
+	var p Polls
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Resolve(tt.actor, tt.ref)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePoll(t *testing.T) {
+	valid := func(change func(poll *models.Poll)) *models.Poll {
+		poll := &models.Poll{Question: "Where?", Options: []string{"Here", "There"}, ChannelID: "channel"}
+		change(poll)
+		return poll
+	}
+
+	tests := []struct {
+		name  string
+		poll  *models.Poll
+		field string
+	}{
+		{name: "valid", poll: valid(func(poll *models.Poll) {})},
+		{name: "no question", poll: valid(func(poll *models.Poll) { poll.Question = "" }), field: "question"},
+		{name: "no options", poll: valid(func(poll *models.Poll) { poll.Options = nil }), field: "options"},
+		{name: "empty option", poll: valid(func(poll *models.Poll) { poll.Options = []string{"Here", ""} }), field: "options"},
+		{name: "invalid results", poll: valid(func(poll *models.Poll) { poll.ResultsVisibility = "never" }), field: "results_visibility"},
+		{name: "quick poll", poll: valid(func(poll *models.Poll) {
+			poll.Reactions = models.ReactionsAny
+			poll.Options = []string{"+1", "-1"}
+		})},
+		{name: "quick poll with text options", poll: valid(func(poll *models.Poll) { poll.Reactions = models.ReactionsAny }), field: "options"},
+		{name: "invalid reactions", poll: valid(func(poll *models.Poll) { poll.Reactions = "all" }), field: "reactions"},
+		{name: "open", poll: valid(func(poll *models.Poll) { poll.OpenOptions = models.OptionsApproval })},
+		{name: "open quick poll", poll: valid(func(poll *models.Poll) {
+			poll.Reactions = models.ReactionsAny
+			poll.Options = []string{"+1"}
+			poll.OpenOptions = models.OptionsOpen
+		}), field: "open"},
+		{name: "invalid open", poll: valid(func(poll *models.Poll) { poll.OpenOptions = "later" }), field: "open_options"},
+		{name: "users without voters", poll: valid(func(poll *models.Poll) { poll.VotersMode = models.VotersUsers }), field: "voters"},
+		{name: "invalid voters", poll: valid(func(poll *models.Poll) { poll.VotersMode = "team" }), field: "voters_mode"},
+		{name: "channel voters without channel", poll: valid(func(poll *models.Poll) {
+			poll.VotersMode = models.VotersChannel
+			poll.ChannelID = ""
+		}), field: "channel_id"},
+		{name: "past deadline", poll: valid(func(poll *models.Poll) { poll.ClosesAt = 1 }), field: "closes_at"},
+		{name: "negative remind", poll: valid(func(poll *models.Poll) { poll.RemindBefore = -1 }), field: "remind_before"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePoll(tt.poll)
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("err = %v, want a ValidationError", err)
+			}
+			if validationErr.Field != tt.field {
+				t.Errorf("Field = %q, want %q", validationErr.Field, tt.field)
+			}
+		})
+	}
+}
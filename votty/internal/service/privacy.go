@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"strings"
+	"votty/internal/models"
+	"votty/internal/storage/tarantool"
+)
+
+// UserData is everything the bot stores about a user.
+type UserData struct {
+	UserID    string             `json:"user_id"`
+	Polls     []*models.Poll     `json:"polls"`
+	Votes     []*models.Vote     `json:"votes"`
+	Templates []*models.Template `json:"templates"`
+	Schedules []*models.Schedule `json:"schedules"`
+	// Suggestions are the options of the user waiting for approval.
+	Suggestions       []*models.Suggestion `json:"suggestions"`
+	RemindersOptedOut bool                 `json:"reminders_opted_out"`
+}
+
+// EraseOptions choose what happens to the data other users depend on.
+type EraseOptions struct {
+	// ReassignTo is the user or @username who takes over the owned polls,
+	// when it is empty the polls are deleted with their votes.
+	ReassignTo string
+	// AnonymizeVotes keeps the votes without the user ID, so the results
+	// of the polls stay the same. Otherwise the votes are deleted.
+	AnonymizeVotes bool
+}
+
+// EraseReport tells what has been erased.
+type EraseReport struct {
+	UserID          string
+	ReassignedTo    string
+	PollsDeleted    int
+	PollsReassigned int
+	Votes           int
+	Templates       int
+	Schedules       int
+	Suggestions     int
+	// Events are the queued webhook events the user ID was redacted from.
+	Events int
+}
+
+// Privacy exports and erases the data of a single user on request of a
+// system admin.
+type Privacy struct {
+	log     *slog.Logger
+	storage *tarantool.Storage
+	client  *model.Client4
+}
+
+func NewPrivacy(log *slog.Logger, storage *tarantool.Storage, client *model.Client4) *Privacy {
+	return &Privacy{log, storage, client}
+}
+
+// Export collects the data of the user, given by ID or @username.
+func (p *Privacy) Export(ctx context.Context, actor Actor, user string) (*UserData, error) {
+	if err := checkAdmin(ctx, p.client, actor.UserID); err != nil {
+		return nil, err
+	}
+	userID, err := resolveUser(ctx, p.client, "user", user)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &UserData{UserID: userID}
+	if data.Polls, err = p.storage.ListPollsByOwner(userID); err != nil {
+		return nil, err
+	}
+	if data.Votes, err = p.storage.VotesByUser(userID); err != nil {
+		return nil, err
+	}
+	if data.Templates, err = p.storage.ListTemplates(userID); err != nil {
+		return nil, err
+	}
+	if data.Schedules, err = p.storage.ListSchedules(userID); err != nil {
+		return nil, err
+	}
+	if data.Suggestions, err = p.storage.UserSuggestions(userID); err != nil {
+		return nil, err
+	}
+	if data.RemindersOptedOut, err = p.storage.IsReminderOptedOut(userID); err != nil {
+		return nil, err
+	}
+
+	p.log.Info("user data has been exported",
+		slog.String("user_id", actor.UserID),
+		slog.String("subject_id", userID),
+	)
+	return data, nil
+}
+
+// Erase removes the user from the bot: owned polls are reassigned or
+// deleted, votes are anonymized or deleted, personal templates,
+// schedules, suggestions and settings are deleted, and the user ID is
+// redacted from the webhook events waiting for delivery.
+func (p *Privacy) Erase(ctx context.Context, actor Actor, user string, opts EraseOptions) (*EraseReport, error) {
+	if err := checkAdmin(ctx, p.client, actor.UserID); err != nil {
+		return nil, err
+	}
+	userID, err := resolveUser(ctx, p.client, "user", user)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &EraseReport{UserID: userID}
+	if opts.ReassignTo != "" {
+		if report.ReassignedTo, err = resolveUser(ctx, p.client, "reassign", opts.ReassignTo); err != nil {
+			return nil, err
+		}
+		if report.ReassignedTo == userID {
+			return nil, &ValidationError{"reassign", "опросы нельзя передать тому же пользователю"}
+		}
+	}
+
+	polls, err := p.storage.ListPollsByOwner(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, poll := range polls {
+		if report.ReassignedTo != "" {
+			err = p.storage.ReassignPoll(poll.ID, report.ReassignedTo)
+			report.PollsReassigned++
+		} else {
+			err = p.storage.DeletePoll(poll.ID)
+			report.PollsDeleted++
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	templates, err := p.storage.ListTemplates(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range templates {
+		if err = p.storage.DeleteTemplate(t.ScopeID, t.Name); err != nil {
+			return nil, err
+		}
+		report.Templates++
+	}
+
+	schedules, err := p.storage.ListSchedules(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sc := range schedules {
+		if err = p.storage.DeleteSchedule(sc.ID); err != nil {
+			return nil, err
+		}
+		report.Schedules++
+	}
+
+	erased, err := p.storage.EraseUser(userID, opts.AnonymizeVotes)
+	if err != nil {
+		return nil, err
+	}
+	report.Votes, report.Suggestions, report.Events = erased.Votes, erased.Suggestions, erased.Events
+
+	p.log.Info("user data has been erased",
+		slog.String("user_id", actor.UserID),
+		slog.String("subject_id", userID),
+		slog.Int("polls_deleted", report.PollsDeleted),
+		slog.Int("polls_reassigned", report.PollsReassigned),
+		slog.Int("votes", report.Votes),
+		slog.Int("events", report.Events),
+	)
+	return report, nil
+}
+
+// resolveUser turns a user ID or @username into a user ID.
+func resolveUser(ctx context.Context, client *model.Client4, field, user string) (string, error) {
+	if username, ok := strings.CutPrefix(user, "@"); ok {
+		u, resp, err := client.GetUserByUsername(ctx, username, "")
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", &ValidationError{field, fmt.Sprintf("пользователь @%s не найден", username)}
+		}
+		if err != nil {
+			return "", err
+		}
+		return u.Id, nil
+	}
+
+	if !model.IsValidId(user) {
+		return "", &ValidationError{field, "нужен ID пользователя или @username"}
+	}
+	return user, nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mattermost/mattermost/server/public/model"
+	"golang.org/x/exp/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"votty/internal/models"
+	"votty/internal/storage/tarantool"
+)
+
+var emojiNameRegex = regexp.MustCompile(`^[a-z0-9_+-]+$`)
+
+// emojiAliases maps the aliases of the system emojis to the names the
+// Mattermost clients use in reactions.
+var emojiAliases = map[string]string{
+	"thumbsup":   "+1",
+	"thumbsdown": "-1",
+}
+
+// EmojiName turns an emoji like :thumbsup: into the name used in reactions.
+func EmojiName(emoji string) string {
+	name := strings.ToLower(strings.Trim(emoji, ":"))
+	if alias, ok := emojiAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+func validateEmojis(options []string) error {
+	for i, option := range options {
+		if !emojiNameRegex.MatchString(option) {
+			return &ValidationError{"options", fmt.Sprintf("вариант %s не является названием эмодзи", option)}
+		}
+		if slices.Contains(options[:i], option) {
+			return &ValidationError{"options", fmt.Sprintf("эмодзи :%s: указан несколько раз", option)}
+		}
+	}
+	return nil
+}
+
+// React turns a reaction to the post of a quick poll into a vote: adding
+// an option emoji votes for it, removing the emoji of the vote moves the
+// vote to the user's latest remaining option reaction or retracts it.
+// Reactions that are not counted are removed from the post. Reactions to
+// other posts and other emojis are ignored.
+func (p *Polls) React(ctx context.Context, actor Actor, postID, emoji string, added bool) error {
+	poll, err := p.storage.PollByPost(postID)
+	if errors.Is(err, tarantool.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if poll.Reactions == models.ReactionsOff || poll.PostID != postID {
+		return nil
+	}
+
+	choice := slices.Index(poll.Options, emoji)
+	if choice < 0 {
+		return nil
+	}
+
+	previous, err := p.storage.SelectVotes(poll.ID, actor.UserID)
+	if err != nil && !errors.Is(err, tarantool.ErrNotFound) {
+		return err
+	}
+
+	if !added {
+		if previous == nil || previous.Choice != uint64(choice) {
+			return nil
+		}
+		if poll.Reactions != models.ReactionsSingle {
+			remaining, err := p.remainingChoice(ctx, actor, poll, choice)
+			if err != nil {
+				return err
+			}
+			if remaining >= 0 {
+				_, err = p.Vote(ctx, actor, poll.ID, remaining+1)
+				return err
+			}
+		}
+		return p.Retract(actor, poll.ID)
+	}
+
+	_, err = p.Vote(ctx, actor, poll.ID, choice+1)
+	switch {
+	case voteRejected(err):
+		p.removeReaction(ctx, actor, poll, choice)
+	case err == nil && poll.Reactions == models.ReactionsSingle && previous != nil && previous.Choice != uint64(choice):
+		p.removeReaction(ctx, actor, poll, int(previous.Choice))
+	}
+	return err
+}
+
+// voteRejected tells whether the vote has been refused for the poll or the
+// user rather than failed.
+func voteRejected(err error) bool {
+	for _, target := range []error{ErrPollNotFound, ErrPollClosed, ErrNotEligible, ErrInvalidOption, ErrVoteLocked} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// remainingChoice returns the option of the latest reaction the user still
+// has on the poll post apart from the removed one, or -1 if there is none.
+func (p *Polls) remainingChoice(ctx context.Context, actor Actor, poll *models.Poll, removed int) (int, error) {
+	reactions, _, err := p.client.GetReactions(ctx, poll.PostID)
+	if err != nil {
+		return -1, err
+	}
+
+	choice, latest := -1, int64(0)
+	for _, reaction := range reactions {
+		if reaction.UserId != actor.UserID || reaction.CreateAt < latest {
+			continue
+		}
+		if i := slices.Index(poll.Options, reaction.EmojiName); i >= 0 && i != removed {
+			choice, latest = i, reaction.CreateAt
+		}
+	}
+	return choice, nil
+}
+
+// removeReaction removes the reaction that does not count as the vote,
+// so that the post shows one reaction per user.
+func (p *Polls) removeReaction(ctx context.Context, actor Actor, poll *models.Poll, choice int) {
+	_, err := p.client.DeleteReaction(ctx, &model.Reaction{
+		UserId:    actor.UserID,
+		PostId:    poll.PostID,
+		EmojiName: poll.Options[choice],
+	})
+	if err != nil {
+		p.log.Warn("Failed to remove the reaction",
+			slog.String("user_id", actor.UserID),
+			slog.String("pollID", poll.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestEmojiName(t *testing.T) {
+	tests := []struct {
+		emoji string
+		want  string
+	}{
+		{":tada:", "tada"},
+		{"tada", "tada"},
+		{":TADA:", "tada"},
+		{":thumbsup:", "+1"},
+		{"thumbsdown", "-1"},
+		{":+1:", "+1"},
+	}
+
+	for _, tt := range tests {
+		if got := EmojiName(tt.emoji); got != tt.want {
+			t.Errorf("EmojiName(%q) = %q, want %q", tt.emoji, got, tt.want)
+		}
+	}
+}
+
+func TestValidateEmojis(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		wantErr bool
+	}{
+		{name: "names", options: []string{"+1", "-1", "white_check_mark", "e-mail"}},
+		{name: "text", options: []string{"yes please"}, wantErr: true},
+		{name: "colons", options: []string{":tada:"}, wantErr: true},
+		{name: "upper case", options: []string{"Tada"}, wantErr: true},
+		{name: "duplicate", options: []string{"tada", "+1", "tada"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateEmojis(tt.options); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVoteRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "accepted", err: nil},
+		{name: "closed", err: ErrPollClosed, want: true},
+		{name: "not eligible", err: ErrNotEligible, want: true},
+		{name: "locked", err: fmt.Errorf("vote: %w", ErrVoteLocked), want: true},
+		{name: "failed", err: errors.New("connection refused")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := voteRejected(tt.err); got != tt.want {
+				t.Errorf("voteRejected(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
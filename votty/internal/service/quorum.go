@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"golang.org/x/exp/slog"
+	"votty/internal/models"
+)
+
+// autoClose evaluates the quorum and threshold rules of the poll after a
+// successful vote. When one of them triggers, the poll is closed and the
+// outcome is announced.
+func (p *Polls) autoClose(ctx context.Context, poll *models.Poll) {
+	if poll.Quorum == 0 && poll.Threshold == 0 {
+		return
+	}
+
+	results, err := p.results(poll)
+	if err != nil {
+		p.log.Error("Failed to get the poll results for auto close",
+			slog.String("pollID", poll.ID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	reason, ok := closeReason(results)
+	if !ok {
+		return
+	}
+
+	p.closeWithOutcome(ctx, results, reason)
+}
+
+// closeWithOutcome ends the poll and announces its results. Votes from
+// the chat, the API and the deadline ticker may try to close the same poll
+// at once, only the one that has actually closed it announces the outcome.
+func (p *Polls) closeWithOutcome(ctx context.Context, results *Results, reason CloseReason) {
+	poll := results.Poll
+	closed, err := p.storage.EndPoll(poll.ID)
+	if err != nil {
+		p.log.Error("Failed to auto close the poll",
+			slog.String("pollID", poll.ID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if !closed {
+		return
+	}
+	poll.IsActive = false
+	p.emit(models.EventPollClosed, poll, nil)
+
+	p.log.Info("poll has been closed automatically",
+		slog.String("pollID", poll.ID),
+		slog.String("reason", reason.Kind),
+	)
+
+	p.notifier.PollClosed(ctx, results, reason)
+}
+
+// closeReason reports whether one of the poll rules has triggered and why.
+func closeReason(results *Results) (CloseReason, bool) {
+	poll := results.Poll
+	if poll.Quorum > 0 && uint64(results.Voters) >= poll.Quorum {
+		return CloseReason{Kind: CloseQuorum}, true
+	}
+	if poll.Threshold > 0 {
+		for i, count := range results.Votes {
+			if uint64(count) >= poll.Threshold {
+				return CloseReason{Kind: CloseThreshold, Option: i + 1}, true
+			}
+		}
+	}
+	return CloseReason{}, false
+}
+
+func totalVotes(votes []int) int {
+	total := 0
+	for _, count := range votes {
+		total += count
+	}
+	return total
+}
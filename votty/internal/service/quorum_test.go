@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+	"votty/internal/models"
+)
+
+func TestCloseReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		poll   models.Poll
+		votes  []int
+		voters int
+		want   CloseReason
+		closes bool
+	}{
+		{name: "no rules", votes: []int{5, 5}, voters: 10},
+		{name: "quorum not met", poll: models.Poll{Quorum: 3}, votes: []int{1, 1}, voters: 2},
+		{name: "quorum met", poll: models.Poll{Quorum: 3}, votes: []int{2, 1}, voters: 3, want: CloseReason{Kind: CloseQuorum}, closes: true},
+		{name: "threshold not met", poll: models.Poll{Threshold: 3}, votes: []int{2, 2}, voters: 4},
+		{name: "threshold met", poll: models.Poll{Threshold: 3}, votes: []int{1, 3}, voters: 4, want: CloseReason{Kind: CloseThreshold, Option: 2}, closes: true},
+		{name: "first option over the threshold", poll: models.Poll{Threshold: 2}, votes: []int{0, 2, 5}, voters: 7, want: CloseReason{Kind: CloseThreshold, Option: 2}, closes: true},
+		{name: "quorum before threshold", poll: models.Poll{Quorum: 2, Threshold: 2}, votes: []int{2}, voters: 2, want: CloseReason{Kind: CloseQuorum}, closes: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, closes := closeReason(&Results{Poll: &tt.poll, Votes: tt.votes, Voters: tt.voters})
+			if got != tt.want || closes != tt.closes {
+				t.Errorf("closeReason() = %+v, %v, want %+v, %v", got, closes, tt.want, tt.closes)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/exp/slog"
+	"time"
+	"votty/internal/models"
+	"votty/internal/storage/tarantool"
+)
+
+const membersPerPage = 200
+
+// remindCooldown is how often the owner of a poll may send the reminders,
+// every run sends a direct message to each non-voter.
+const remindCooldown = time.Hour
+
+// Remind asks the members of the poll channel who have not voted yet to
+// vote, at most once per remindCooldown. It returns the number of
+// recipients, the reminders themselves are sent in the background.
+func (p *Polls) Remind(ctx context.Context, actor Actor, pollID string) (int, error) {
+	poll, err := p.Get(pollID)
+	if err != nil {
+		return 0, err
+	}
+	if poll.OwnerID != actor.UserID {
+		return 0, ErrNotOwner
+	}
+	if !poll.IsActive || poll.VotersMode != models.VotersChannel {
+		return 0, ErrNotRemindable
+	}
+
+	err = p.storage.ClaimReminder(poll.ID, remindCooldown)
+	switch {
+	case errors.Is(err, tarantool.ErrTooSoon):
+		return 0, ErrRemindTooSoon
+	case errors.Is(err, tarantool.ErrNotFound):
+		return 0, ErrPollNotFound
+	case err != nil:
+		return 0, err
+	}
+
+	nonVoters, err := p.nonVoters(ctx, poll)
+	if err != nil {
+		return 0, err
+	}
+
+	go p.notifier.Remind(context.WithoutCancel(ctx), poll, nonVoters)
+
+	p.log.Info("reminders have been requested",
+		slog.String("user_id", actor.UserID),
+		slog.String("pollID", poll.ID),
+		slog.Int("recipients", len(nonVoters)),
+	)
+	return len(nonVoters), nil
+}
+
+// SetReminders turns the reminders about polls on or off for the actor.
+func (p *Polls) SetReminders(actor Actor, enabled bool) error {
+	return p.storage.SetReminderOptOut(actor.UserID, !enabled)
+}
+
+// ProcessDeadlines closes the polls whose deadline has passed and sends
+// the scheduled reminders for the ones approaching it.
+func (p *Polls) ProcessDeadlines(ctx context.Context) {
+	polls, err := p.storage.PollsWithDeadline()
+	if err != nil {
+		p.log.Error("Failed to select polls with deadline",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	now := time.Now().Unix()
+	for _, poll := range withoutDeleted(polls) {
+		switch {
+		case poll.ClosesAt <= now:
+			results, err := p.results(poll)
+			if err != nil {
+				p.log.Error("Failed to get the poll results for deadline",
+					slog.String("pollID", poll.ID),
+					slog.String("error", err.Error()),
+				)
+				continue
+			}
+			p.closeWithOutcome(ctx, results, CloseReason{Kind: CloseDeadline})
+
+		case poll.RemindBefore > 0 && !poll.Reminded && poll.ClosesAt-poll.RemindBefore <= now:
+			if poll.VotersMode == models.VotersChannel {
+				nonVoters, err := p.nonVoters(ctx, poll)
+				if err != nil {
+					p.log.Error("Failed to find non-voters",
+						slog.String("pollID", poll.ID),
+						slog.String("error", err.Error()),
+					)
+					continue
+				}
+				go p.notifier.Remind(ctx, poll, nonVoters)
+			}
+
+			if err = p.storage.MarkReminded(poll.ID); err != nil {
+				p.log.Error("Failed to mark the poll as reminded",
+					slog.String("pollID", poll.ID),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}
+}
+
+// nonVoters returns the members of the poll channel who have not voted yet
+// and have not opted out of reminders.
+func (p *Polls) nonVoters(ctx context.Context, poll *models.Poll) ([]string, error) {
+	voters, err := p.storage.PollVoters(poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	voted := make(map[string]bool, len(voters))
+	for _, id := range voters {
+		voted[id] = true
+	}
+
+	var result []string
+	for page := 0; ; page++ {
+		members, _, err := p.client.GetChannelMembers(ctx, poll.ChannelID, page, membersPerPage, "")
+		if err != nil {
+			return nil, err
+		}
+
+		var candidates []string
+		for _, member := range members {
+			if !voted[member.UserId] && member.UserId != p.botID {
+				candidates = append(candidates, member.UserId)
+			}
+		}
+		if len(candidates) > 0 {
+			optedOut, err := p.storage.ReminderOptedOut(candidates)
+			if err != nil {
+				return nil, err
+			}
+			for _, userID := range candidates {
+				if !optedOut[userID] {
+					result = append(result, userID)
+				}
+			}
+		}
+
+		if len(members) < membersPerPage {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"github.com/mattermost/mattermost/server/public/model"
+	"golang.org/x/exp/slog"
+	"time"
+	"votty/internal/models"
+	"votty/internal/storage/tarantool"
+)
+
+// RetentionReport lists what the retention policy removes on its next run.
+type RetentionReport struct {
+	// PurgeAfter and AnonymizeAfter are the policy, 0 disables a rule.
+	PurgeAfter     time.Duration
+	AnonymizeAfter time.Duration
+	// Purge are the closed polls to delete with their votes.
+	Purge []*models.Poll
+	// Anonymize are the closed polls whose votes lose their user IDs.
+	Anonymize []*models.Poll
+}
+
+// retentionBatch bounds the polls a single run purges and anonymizes, the
+// rest is left to the next runs.
+const retentionBatch = 500
+
+// Retention applies the data retention policy to closed polls: they are
+// purged some time after closing, and their votes may be anonymized earlier.
+type Retention struct {
+	log            *slog.Logger
+	storage        *tarantool.Storage
+	client         *model.Client4
+	purgeAfter     time.Duration
+	anonymizeAfter time.Duration
+}
+
+func NewRetention(log *slog.Logger, storage *tarantool.Storage, client *model.Client4, purgeAfter, anonymizeAfter time.Duration) *Retention {
+	return &Retention{log, storage, client, purgeAfter, anonymizeAfter}
+}
+
+// Report is the dry run of the policy, only system admins may request it.
+func (r *Retention) Report(ctx context.Context, actor Actor) (*RetentionReport, error) {
+	if err := checkAdmin(ctx, r.client, actor.UserID); err != nil {
+		return nil, err
+	}
+	return r.due(time.Now())
+}
+
+func (r *Retention) due(now time.Time) (*RetentionReport, error) {
+	report := &RetentionReport{PurgeAfter: r.purgeAfter, AnonymizeAfter: r.anonymizeAfter}
+
+	if r.purgeAfter > 0 {
+		polls, err := r.storage.ClosedPolls(now.Add(-r.purgeAfter).Unix(), retentionBatch)
+		if err != nil {
+			return nil, err
+		}
+		report.Purge = polls
+	}
+
+	if r.anonymizeAfter > 0 {
+		polls, err := r.storage.PollsToAnonymize(now.Add(-r.anonymizeAfter).Unix(), retentionBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		purged := make(map[string]bool, len(report.Purge))
+		for _, poll := range report.Purge {
+			purged[poll.ID] = true
+		}
+		for _, poll := range polls {
+			if !purged[poll.ID] {
+				report.Anonymize = append(report.Anonymize, poll)
+			}
+		}
+	}
+	return report, nil
+}
+
+// Run applies the policy.
+func (r *Retention) Run() {
+	if r.purgeAfter == 0 && r.anonymizeAfter == 0 {
+		return
+	}
+
+	report, err := r.due(time.Now())
+	if err != nil {
+		r.log.Error("Failed to select polls for retention",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	for _, poll := range report.Purge {
+		if err = r.storage.DeletePoll(poll.ID); err != nil {
+			r.log.Error("Failed to purge the poll",
+				slog.String("pollID", poll.ID),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		r.log.Info("poll has been purged by the retention policy",
+			slog.String("pollID", poll.ID),
+		)
+	}
+
+	for _, poll := range report.Anonymize {
+		votes, err := r.storage.AnonymizeVotes(poll.ID)
+		if err != nil {
+			r.log.Error("Failed to anonymize the votes",
+				slog.String("pollID", poll.ID),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		r.log.Info("votes have been anonymized by the retention policy",
+			slog.String("pollID", poll.ID),
+			slog.Int("votes", votes),
+		)
+	}
+}
+
+// checkAdmin returns ErrNotAdmin unless the user is a Mattermost system
+// admin.
+func checkAdmin(ctx context.Context, client *model.Client4, userID string) error {
+	user, _, err := client.GetUser(ctx, userID, "")
+	if err != nil {
+		return err
+	}
+	if !user.IsSystemAdmin() {
+		return ErrNotAdmin
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/robfig/cron/v3"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"strings"
+	"time"
+	"votty/internal/models"
+	"votty/internal/storage/tarantool"
+)
+
+// catchUpWindow is how late a missed run may still create its poll after
+// downtime. Several missed runs always produce a single poll.
+const catchUpWindow = 12 * time.Hour
+
+// Schedules manages recurring polls created from templates.
+type Schedules struct {
+	log       *slog.Logger
+	storage   *tarantool.Storage
+	client    *model.Client4
+	polls     *Polls
+	templates *Templates
+}
+
+func NewSchedules(log *slog.Logger, storage *tarantool.Storage, client *model.Client4, polls *Polls, templates *Templates) *Schedules {
+	return &Schedules{log, storage, client, polls, templates}
+}
+
+// Add creates a schedule of the actor's template. The polls are posted to
+// the actor's channel or to the channel of the actor's team named by
+// channelName.
+func (s *Schedules) Add(ctx context.Context, actor Actor, sc *models.Schedule, templateName, channelName string) (*models.Schedule, error) {
+	sc.OwnerID = actor.UserID
+	sc.ChannelID = actor.ChannelID
+	sc.Cron = strings.TrimSpace(sc.Cron)
+	if sc.Timezone == "" {
+		sc.Timezone = "UTC"
+	}
+	if sc.CloseAfter < 0 {
+		return nil, &ValidationError{"close", "флаг --close принимает длительность, например ```--close=24h```"}
+	}
+
+	next, err := nextRun(sc, time.Now())
+	if err != nil {
+		return nil, &ValidationError{"cron", fmt.Sprintf("неверное расписание: %s. Ожидается cron выражение из пяти полей, например ```0 10 * * 5```, и часовой пояс вида ```Europe/Moscow```", err.Error())}
+	}
+	sc.NextRun = next.Unix()
+
+	t, err := s.templates.Find(ctx, actor, templateName)
+	if err != nil {
+		return nil, err
+	}
+	sc.TemplateScopeID = t.ScopeID
+	sc.TemplateName = t.Name
+
+	if channelName != "" {
+		teamID, err := s.templates.teamID(ctx, actor)
+		if err != nil {
+			return nil, err
+		}
+		channelName = strings.TrimPrefix(channelName, "~")
+		channel, _, err := s.client.GetChannelByName(ctx, channelName, teamID, "")
+		if err != nil {
+			return nil, &ValidationError{"channel", fmt.Sprintf("канал ~%s не найден", channelName)}
+		}
+		// the bot posts on behalf of the owner, who must be able to post there
+		_, resp, err := s.client.GetChannelMember(ctx, channel.Id, actor.UserID, "")
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, &ValidationError{"channel", fmt.Sprintf("ты не состоишь в канале ~%s", channelName)}
+		}
+		if err != nil {
+			return nil, err
+		}
+		sc.ChannelID = channel.Id
+	}
+
+	sc.ID, err = gonanoid.New(10)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.storage.CreateSchedule(sc); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("schedule has been created",
+		slog.String("user_id", actor.UserID),
+		slog.String("scheduleID", sc.ID),
+		slog.String("cron", sc.Cron),
+	)
+	return sc, nil
+}
+
+func (s *Schedules) List(actor Actor) ([]*models.Schedule, error) {
+	return s.storage.ListSchedules(actor.UserID)
+}
+
+// Pause stops creating polls until the schedule is resumed.
+func (s *Schedules) Pause(actor Actor, scheduleID string) (*models.Schedule, error) {
+	sc, err := s.owned(actor, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.storage.SetScheduleNextRun(sc.ID, sc.NextRun, true); err != nil {
+		return nil, err
+	}
+	sc.Paused = true
+	s.logChange(actor, sc, "pause")
+	return sc, nil
+}
+
+// Resume continues the schedule from its next run after now, the runs
+// missed while it was paused are skipped.
+func (s *Schedules) Resume(actor Actor, scheduleID string) (*models.Schedule, error) {
+	sc, err := s.owned(actor, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	next, err := nextRun(sc, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	sc.NextRun = next.Unix()
+	if err = s.storage.SetScheduleNextRun(sc.ID, sc.NextRun, false); err != nil {
+		return nil, err
+	}
+	sc.Paused = false
+	s.logChange(actor, sc, "resume")
+	return sc, nil
+}
+
+func (s *Schedules) Delete(actor Actor, scheduleID string) error {
+	sc, err := s.owned(actor, scheduleID)
+	if err != nil {
+		return err
+	}
+	if err = s.storage.DeleteSchedule(sc.ID); err != nil {
+		return err
+	}
+	s.logChange(actor, sc, "delete")
+	return nil
+}
+
+func (s *Schedules) owned(actor Actor, scheduleID string) (*models.Schedule, error) {
+	sc, err := s.storage.GetSchedule(scheduleID)
+	if errors.Is(err, tarantool.ErrNotFound) {
+		return nil, ErrScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sc.OwnerID != actor.UserID {
+		return nil, ErrNotOwner
+	}
+	return sc, nil
+}
+
+func (s *Schedules) logChange(actor Actor, sc *models.Schedule, action string) {
+	s.log.Info("schedule has been changed",
+		slog.String("user_id", actor.UserID),
+		slog.String("scheduleID", sc.ID),
+		slog.String("action", action),
+	)
+}
+
+// Run creates the polls of all due schedules. The next run is stored
+// before the poll is created, so a failure never repeats a poll.
+func (s *Schedules) Run(ctx context.Context) {
+	now := time.Now()
+
+	schedules, err := s.storage.DueSchedules(now.Unix())
+	if err != nil {
+		s.log.Error("Failed to select due schedules",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	for _, sc := range schedules {
+		next, err := nextRun(sc, now)
+		if err != nil {
+			s.log.Error("Invalid schedule, pausing it",
+				slog.String("scheduleID", sc.ID),
+				slog.String("error", err.Error()),
+			)
+			if err = s.storage.SetScheduleNextRun(sc.ID, sc.NextRun, true); err != nil {
+				s.log.Error("Failed to pause the schedule",
+					slog.String("scheduleID", sc.ID),
+					slog.String("error", err.Error()),
+				)
+			}
+			continue
+		}
+
+		if err = s.storage.SetScheduleNextRun(sc.ID, next.Unix(), false); err != nil {
+			s.log.Error("Failed to store the next schedule run",
+				slog.String("scheduleID", sc.ID),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+
+		if missed, skip := missedRun(sc, now); skip {
+			s.log.Warn("Skipping a schedule run missed during downtime",
+				slog.String("scheduleID", sc.ID),
+				slog.String("missed", missed.String()),
+			)
+			continue
+		}
+
+		s.run(ctx, sc)
+	}
+}
+
+func (s *Schedules) run(ctx context.Context, sc *models.Schedule) {
+	t, err := s.storage.GetTemplate(sc.TemplateScopeID, sc.TemplateName)
+	if err != nil {
+		s.log.Error("Failed to get the template of the schedule",
+			slog.String("scheduleID", sc.ID),
+			slog.String("template", sc.TemplateName),
+			slog.String("error", err.Error()),
+		)
+		if errors.Is(err, tarantool.ErrNotFound) {
+			err = ErrTemplateNotFound
+		}
+		s.polls.notifier.ScheduleFailed(ctx, sc, err)
+		return
+	}
+
+	var flags []string
+	if sc.CloseAfter > 0 {
+		flags = append(flags, fmt.Sprintf("--deadline=%ds", sc.CloseAfter))
+	}
+
+	poll, err := s.polls.CreateFromTemplate(ctx, Actor{UserID: sc.OwnerID, ChannelID: sc.ChannelID}, t, flags...)
+	if err != nil {
+		s.log.Error("Failed to create the scheduled poll",
+			slog.String("scheduleID", sc.ID),
+			slog.String("error", err.Error()),
+		)
+		s.polls.notifier.ScheduleFailed(ctx, sc, err)
+		return
+	}
+	s.polls.Announce(ctx, poll)
+
+	s.log.Info("scheduled poll has been created",
+		slog.String("scheduleID", sc.ID),
+		slog.String("template", sc.TemplateName),
+	)
+}
+
+// missedRun returns how late the due run of the schedule is and whether it
+// is past the catch-up window and must be skipped.
+func missedRun(sc *models.Schedule, now time.Time) (time.Duration, bool) {
+	missed := now.Sub(time.Unix(sc.NextRun, 0))
+	return missed, missed > catchUpWindow
+}
+
+func nextRun(sc *models.Schedule, after time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(sc.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	schedule, err := cron.ParseStandard(sc.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return schedule.Next(after.In(loc)), nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"votty/internal/models"
+)
+
+func TestNextRun(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		cron     string
+		timezone string
+		after    time.Time
+		want     time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "later the same day",
+			cron:     "0 10 * * *",
+			timezone: "UTC",
+			after:    time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC),
+			want:     time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "exactly at the run",
+			cron:     "0 10 * * *",
+			timezone: "UTC",
+			after:    time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC),
+			want:     time.Date(2025, 1, 7, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekdays skip the weekend",
+			cron:     "30 9 * * 1-5",
+			timezone: "UTC",
+			after:    time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC), // Friday
+			want:     time.Date(2025, 1, 13, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "evaluated in the timezone",
+			cron:     "0 10 * * *",
+			timezone: "Europe/Moscow",
+			after:    time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC), // 11:00 in Moscow
+			want:     time.Date(2025, 1, 7, 10, 0, 0, 0, moscow),
+		},
+		{
+			name:     "several missed runs give the first one after now",
+			cron:     "0 * * * *",
+			timezone: "UTC",
+			after:    time.Date(2025, 1, 6, 17, 45, 0, 0, time.UTC),
+			want:     time.Date(2025, 1, 6, 18, 0, 0, 0, time.UTC),
+		},
+		{name: "invalid cron", cron: "every day", timezone: "UTC", wantErr: true},
+		{name: "six fields", cron: "0 0 10 * * *", timezone: "UTC", wantErr: true},
+		{name: "invalid timezone", cron: "0 10 * * *", timezone: "Mars/Olympus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &models.Schedule{Cron: tt.cron, Timezone: tt.timezone}
+			got, err := nextRun(sc, tt.after)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("nextRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissedRun(t *testing.T) {
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		nextRun time.Time
+		missed  time.Duration
+		skip    bool
+	}{
+		{name: "on time", nextRun: now, missed: 0},
+		{name: "one tick late", nextRun: now.Add(-30 * time.Second), missed: 30 * time.Second},
+		{name: "at the window", nextRun: now.Add(-catchUpWindow), missed: catchUpWindow},
+		{name: "past the window", nextRun: now.Add(-catchUpWindow - time.Second), missed: catchUpWindow + time.Second, skip: true},
+		{name: "days of downtime", nextRun: now.Add(-72 * time.Hour), missed: 72 * time.Hour, skip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missed, skip := missedRun(&models.Schedule{NextRun: tt.nextRun.Unix()}, now)
+			if missed != tt.missed || skip != tt.skip {
+				t.Errorf("missedRun() = %v, %v, want %v, %v", missed, skip, tt.missed, tt.skip)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"golang.org/x/exp/slog"
+	"strings"
+	"time"
+	"votty/internal/models"
+	"votty/internal/storage/tarantool"
+)
+
+// Suggested describes an option suggested by a voter.
+type Suggested struct {
+	Poll       *models.Poll
+	Suggestion *models.Suggestion
+	// Pending is set while the option waits for the approval of the owner,
+	// otherwise it has been added to the poll.
+	Pending bool
+}
+
+// Suggest adds the actor's option to an open poll. Polls with
+// OptionsApproval keep the option until the owner approves it, the
+// options of the owner are added right away.
+func (p *Polls) Suggest(ctx context.Context, actor Actor, pollID, option string) (*Suggested, error) {
+	poll, err := p.Get(pollID)
+	if err != nil {
+		return nil, err
+	}
+	if !poll.IsActive {
+		return nil, ErrPollClosed
+	}
+	if poll.OpenOptions == models.OptionsClosed {
+		return nil, ErrOptionsClosed
+	}
+
+	eligible, err := p.isEligible(ctx, poll, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !eligible {
+		return nil, ErrNotEligible
+	}
+
+	option = strings.Join(strings.Fields(option), " ")
+	if err = checkOption(poll, option, -1); err != nil {
+		return nil, err
+	}
+	if len(poll.Options) >= p.maxOptions {
+		return nil, p.tooManyOptions()
+	}
+
+	suggestion := &models.Suggestion{PollID: poll.ID, UserID: actor.UserID, Option: option, CreatedAt: time.Now().Unix()}
+	if poll.OpenOptions == models.OptionsOpen || poll.OwnerID == actor.UserID {
+		if err = p.addOption(ctx, actor, poll, option); err != nil {
+			return nil, err
+		}
+		return &Suggested{Poll: poll, Suggestion: suggestion}, nil
+	}
+
+	pending, err := p.storage.PollSuggestions(poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range pending {
+		if sameOption(s.Option, option) {
+			return nil, &ValidationError{"options", "такой вариант уже ждет одобрения создателя опроса"}
+		}
+	}
+
+	if suggestion.ID, err = gonanoid.New(10); err != nil {
+		return nil, err
+	}
+	if err = p.storage.CreateSuggestion(suggestion); err != nil {
+		return nil, err
+	}
+
+	p.log.Info("Suggest",
+		slog.String("user_id", actor.UserID),
+		slog.String("pollID", poll.ID),
+		slog.String("suggestionID", suggestion.ID),
+	)
+	p.notifier.OptionSuggested(ctx, poll, suggestion)
+
+	return &Suggested{Poll: poll, Suggestion: suggestion, Pending: true}, nil
+}
+
+// Approve adds the suggested option to the actor's poll and tells the
+// user who suggested it. The option is added and the suggestion deleted at
+// once, so a repeated approval gets ErrSuggestionNotFound.
+func (p *Polls) Approve(ctx context.Context, actor Actor, suggestionID string) (*Suggested, error) {
+	suggestion, err := p.suggestion(suggestionID)
+	if err != nil {
+		return nil, err
+	}
+	poll, err := p.editable(actor, suggestion.PollID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkOption(poll, suggestion.Option, -1); err != nil {
+		return nil, err
+	}
+	if len(poll.Options) >= p.maxOptions {
+		return nil, p.tooManyOptions()
+	}
+
+	err = p.storage.ApproveSuggestion(suggestion.ID, p.maxOptions)
+	switch {
+	case errors.Is(err, tarantool.ErrNotFound):
+		return nil, ErrSuggestionNotFound
+	case errors.Is(err, tarantool.ErrTooManyOptions):
+		return nil, p.tooManyOptions()
+	case err != nil:
+		return nil, editError(err)
+	}
+	poll.Options = append(poll.Options, suggestion.Option)
+
+	p.edited(ctx, actor, poll)
+	p.notifier.SuggestionDecided(ctx, poll, suggestion, true)
+	return &Suggested{Poll: poll, Suggestion: suggestion}, nil
+}
+
+// Reject drops the suggested option of the actor's poll and tells the user
+// who suggested it.
+func (p *Polls) Reject(ctx context.Context, actor Actor, suggestionID string) (*Suggested, error) {
+	suggestion, err := p.suggestion(suggestionID)
+	if err != nil {
+		return nil, err
+	}
+	poll, err := p.Get(suggestion.PollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.OwnerID != actor.UserID {
+		return nil, ErrNotOwner
+	}
+
+	err = p.storage.DeleteSuggestion(suggestion.ID)
+	if errors.Is(err, tarantool.ErrNotFound) {
+		return nil, ErrSuggestionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.log.Info("Reject",
+		slog.String("user_id", actor.UserID),
+		slog.String("pollID", poll.ID),
+		slog.String("suggestionID", suggestion.ID),
+	)
+	p.notifier.SuggestionDecided(ctx, poll, suggestion, false)
+	return &Suggested{Poll: poll, Suggestion: suggestion}, nil
+}
+
+func (p *Polls) suggestion(suggestionID string) (*models.Suggestion, error) {
+	suggestion, err := p.storage.GetSuggestion(suggestionID)
+	if errors.Is(err, tarantool.ErrNotFound) {
+		return nil, ErrSuggestionNotFound
+	}
+	return suggestion, err
+}
@@ -0,0 +1,179 @@
+//go:build integration
+
+package service
+
+import (
+	"context"
+	"errors"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"golang.org/x/exp/slog"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+	"votty/internal/config"
+	"votty/internal/models"
+	"votty/internal/storage/tarantool"
+)
+
+// recorder is a Notifier that remembers what it has been asked to send.
+type recorder struct {
+	updated   int
+	suggested []*models.Suggestion
+	decided   map[string]bool
+}
+
+func (r *recorder) PollCreated(context.Context, *models.Poll) string  { return "" }
+func (r *recorder) PollClosed(context.Context, *Results, CloseReason) {}
+func (r *recorder) Remind(context.Context, *models.Poll, []string)    {}
+func (r *recorder) PollUpdated(context.Context, *models.Poll)         { r.updated++ }
+func (r *recorder) VotesCleared(context.Context, *models.Poll, string, []string) {
+}
+func (r *recorder) OptionSuggested(_ context.Context, _ *models.Poll, s *models.Suggestion) {
+	r.suggested = append(r.suggested, s)
+}
+func (r *recorder) ScheduleFailed(context.Context, *models.Schedule, error) {}
+func (r *recorder) SuggestionDecided(_ context.Context, _ *models.Poll, s *models.Suggestion, approved bool) {
+	r.decided[s.UserID] = approved
+}
+
+// testPolls runs the service against the Tarantool at TARANTOOL_HOST, the
+// tests are skipped without it.
+func testPolls(t *testing.T, maxOptions int) (*Polls, *tarantool.Storage, *recorder) {
+	t.Helper()
+	host := os.Getenv("TARANTOOL_HOST")
+	if host == "" {
+		t.Skip("TARANTOOL_HOST is not set")
+	}
+	user := os.Getenv("TARANTOOL_USER")
+	if user == "" {
+		user = "guest"
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	storage := tarantool.New(log, &config.Config{TarantoolHost: host, TarantoolUser: user, TarantoolPassword: os.Getenv("TARANTOOL_PASSWORD")})
+	if storage == nil {
+		t.Fatalf("failed to connect to Tarantool at %s", host)
+	}
+	t.Cleanup(func() { storage.Conn.Close() })
+
+	notifier := &recorder{decided: make(map[string]bool)}
+	return NewPolls(log, storage, nil, notifier, "bot", time.Hour, maxOptions, false), storage, notifier
+}
+
+func createOpenPoll(t *testing.T, polls *Polls, storage *tarantool.Storage, owner Actor, mode string) *models.Poll {
+	t.Helper()
+	poll, err := polls.Create(context.Background(), owner, &models.Poll{
+		Question:    "Where should we go?",
+		Options:     []string{"Paris", "Rome"},
+		OpenOptions: mode,
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	t.Cleanup(func() { storage.DeletePoll(poll.ID) })
+	return poll
+}
+
+func TestSuggestWithApproval(t *testing.T) {
+	ctx := context.Background()
+	polls, storage, notifier := testPolls(t, 3)
+	owner := Actor{UserID: "owner-" + gonanoid.Must(6)}
+	alice := Actor{UserID: "alice-" + gonanoid.Must(6)}
+	bob := Actor{UserID: "bob-" + gonanoid.Must(6)}
+	poll := createOpenPoll(t, polls, storage, owner, models.OptionsApproval)
+
+	suggested, err := polls.Suggest(ctx, alice, poll.ID, "  new   york ")
+	if err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	if !suggested.Pending || suggested.Suggestion.Option != "new york" {
+		t.Fatalf("Suggest = %+v, want a pending \"new york\"", suggested.Suggestion)
+	}
+	if len(notifier.suggested) != 1 {
+		t.Fatalf("owner has been asked %v times, want once", len(notifier.suggested))
+	}
+
+	var validationErr *ValidationError
+	for _, option := range []string{"New York", "PARIS", " rome"} {
+		if _, err = polls.Suggest(ctx, bob, poll.ID, option); !errors.As(err, &validationErr) {
+			t.Fatalf("Suggest(%q) = %v, want a duplicate ValidationError", option, err)
+		}
+	}
+
+	if _, err = polls.Approve(ctx, bob, suggested.Suggestion.ID); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("Approve by a voter = %v, want ErrNotOwner", err)
+	}
+	approved, err := polls.Approve(ctx, owner, suggested.Suggestion.ID)
+	if err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	want := []string{"Paris", "Rome", "new york"}
+	if !reflect.DeepEqual(approved.Poll.Options, want) {
+		t.Fatalf("options after Approve = %q, want %q", approved.Poll.Options, want)
+	}
+	stored, err := storage.GetPoll(poll.ID)
+	if err != nil {
+		t.Fatalf("GetPoll: %v", err)
+	}
+	if !reflect.DeepEqual(stored.Options, want) {
+		t.Fatalf("stored options = %q, want %q", stored.Options, want)
+	}
+	if !notifier.decided[alice.UserID] || notifier.updated != 1 {
+		t.Fatalf("decided = %v, updated = %v, want alice told and the post refreshed", notifier.decided, notifier.updated)
+	}
+	if _, err = polls.Approve(ctx, owner, suggested.Suggestion.ID); !errors.Is(err, ErrSuggestionNotFound) {
+		t.Fatalf("second Approve = %v, want ErrSuggestionNotFound", err)
+	}
+
+	if _, err = polls.Suggest(ctx, bob, poll.ID, "Berlin"); !errors.As(err, &validationErr) {
+		t.Fatalf("Suggest over the limit = %v, want a ValidationError", err)
+	}
+}
+
+func TestSuggestReject(t *testing.T) {
+	ctx := context.Background()
+	polls, storage, notifier := testPolls(t, 10)
+	owner := Actor{UserID: "owner-" + gonanoid.Must(6)}
+	alice := Actor{UserID: "alice-" + gonanoid.Must(6)}
+	poll := createOpenPoll(t, polls, storage, owner, models.OptionsApproval)
+
+	suggested, err := polls.Suggest(ctx, alice, poll.ID, "Berlin")
+	if err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	if _, err = polls.Reject(ctx, owner, suggested.Suggestion.ID); err != nil {
+		t.Fatalf("Reject: %v", err)
+	}
+	if approved, told := notifier.decided[alice.UserID]; !told || approved {
+		t.Fatalf("alice has not been told about the rejection")
+	}
+	if _, err = storage.GetSuggestion(suggested.Suggestion.ID); !errors.Is(err, tarantool.ErrNotFound) {
+		t.Fatalf("rejected suggestion is still stored: %v", err)
+	}
+}
+
+func TestSuggestOpen(t *testing.T) {
+	ctx := context.Background()
+	polls, storage, notifier := testPolls(t, 10)
+	owner := Actor{UserID: "owner-" + gonanoid.Must(6)}
+	alice := Actor{UserID: "alice-" + gonanoid.Must(6)}
+	poll := createOpenPoll(t, polls, storage, owner, models.OptionsOpen)
+
+	suggested, err := polls.Suggest(ctx, alice, poll.ID, "Berlin")
+	if err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	if suggested.Pending || len(suggested.Poll.Options) != 3 || notifier.updated != 1 {
+		t.Fatalf("Suggest = %+v, want Berlin added right away", suggested)
+	}
+	if _, err = polls.Vote(ctx, alice, poll.ID, 3); err != nil {
+		t.Fatalf("Vote for the suggested option: %v", err)
+	}
+
+	closed := createOpenPoll(t, polls, storage, owner, models.OptionsClosed)
+	if _, err = polls.Suggest(ctx, alice, closed.ID, "Berlin"); !errors.Is(err, ErrOptionsClosed) {
+		t.Fatalf("Suggest to a closed poll = %v, want ErrOptionsClosed", err)
+	}
+}
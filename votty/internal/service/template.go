@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/mattermost/mattermost/server/public/model"
+	"golang.org/x/exp/slog"
+	"regexp"
+	"strings"
+	"votty/internal/models"
+	"votty/internal/storage/tarantool"
+)
+
+var templateNameRegex = regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)
+
+// Templates manages the poll templates of users and teams.
+type Templates struct {
+	log     *slog.Logger
+	storage *tarantool.Storage
+	client  *model.Client4
+}
+
+func NewTemplates(log *slog.Logger, storage *tarantool.Storage, client *model.Client4) *Templates {
+	return &Templates{log, storage, client}
+}
+
+// Save stores the template in the actor's scope or, for team templates,
+// in the team of the actor's channel. A team template of another user
+// cannot be overwritten.
+func (t *Templates) Save(ctx context.Context, actor Actor, tmpl *models.Template) error {
+	if !templateNameRegex.MatchString(tmpl.Name) {
+		return &ValidationError{"name", "название шаблона должно быть одним словом из букв, цифр, ```_``` и ```-``` и идти после флагов"}
+	}
+
+	tmpl.Question = strings.TrimSpace(tmpl.Question)
+	for i := range tmpl.Options {
+		tmpl.Options[i] = strings.TrimSpace(tmpl.Options[i])
+	}
+
+	// The flags are applied again on every /create, here they are only
+	// checked to reject a broken template early.
+	if _, err := ParsePollFlags(&models.Poll{}, strings.Join(tmpl.Flags, " ")); err != nil {
+		return err
+	}
+
+	tmpl.OwnerID = actor.UserID
+	tmpl.ScopeID = actor.UserID
+	if tmpl.Scope == models.TemplateScopeTeam {
+		teamID, err := t.teamID(ctx, actor)
+		if err != nil {
+			return err
+		}
+		tmpl.ScopeID = teamID
+	} else {
+		tmpl.Scope = models.TemplateScopeUser
+	}
+
+	existing, err := t.storage.GetTemplate(tmpl.ScopeID, tmpl.Name)
+	if err != nil && !errors.Is(err, tarantool.ErrNotFound) {
+		return err
+	}
+	if existing != nil && existing.OwnerID != actor.UserID {
+		return ErrNotOwner
+	}
+
+	if err = t.storage.SaveTemplate(tmpl); err != nil {
+		return err
+	}
+
+	t.log.Info("template has been saved",
+		slog.String("user_id", actor.UserID),
+		slog.String("template", tmpl.Name),
+		slog.String("scope", tmpl.Scope),
+	)
+	return nil
+}
+
+// List returns the actor's own templates and the templates of the team of
+// the actor's channel.
+func (t *Templates) List(ctx context.Context, actor Actor) (own, team []*models.Template, err error) {
+	own, err = t.storage.ListTemplates(actor.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Team templates are only an addition, the list works in direct
+	// messages too.
+	teamID, err := t.teamID(ctx, actor)
+	if err != nil {
+		return own, nil, nil
+	}
+
+	team, err = t.storage.ListTemplates(teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return own, team, nil
+}
+
+// Delete removes the actor's template, or the team one if team is set.
+func (t *Templates) Delete(ctx context.Context, actor Actor, name string, team bool) error {
+	scopeID := actor.UserID
+	if team {
+		teamID, err := t.teamID(ctx, actor)
+		if err != nil {
+			return err
+		}
+		scopeID = teamID
+	}
+
+	tmpl, err := t.storage.GetTemplate(scopeID, name)
+	if errors.Is(err, tarantool.ErrNotFound) {
+		return ErrTemplateNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if tmpl.OwnerID != actor.UserID {
+		return ErrNotOwner
+	}
+
+	return t.storage.DeleteTemplate(tmpl.ScopeID, tmpl.Name)
+}
+
+// Find returns the actor's template or, if there is none with that name,
+// the one of the team of the actor's channel.
+func (t *Templates) Find(ctx context.Context, actor Actor, name string) (*models.Template, error) {
+	tmpl, err := t.storage.GetTemplate(actor.UserID, name)
+	if !errors.Is(err, tarantool.ErrNotFound) {
+		return tmpl, err
+	}
+
+	teamID, err := t.teamID(ctx, actor)
+	if errors.Is(err, ErrNoTeam) {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err = t.storage.GetTemplate(teamID, name)
+	if errors.Is(err, tarantool.ErrNotFound) {
+		return nil, ErrTemplateNotFound
+	}
+	return tmpl, err
+}
+
+func (t *Templates) teamID(ctx context.Context, actor Actor) (string, error) {
+	teamID, err := channelTeamID(ctx, t.client, actor.ChannelID)
+	if err != nil {
+		return "", err
+	}
+	if teamID == "" {
+		return "", ErrNoTeam
+	}
+	return teamID, nil
+}
+
+// CreateFromTemplate creates a poll from the template. The extra flags are
+// applied after the template ones and override them.
+func (p *Polls) CreateFromTemplate(ctx context.Context, actor Actor, tmpl *models.Template, extraFlags ...string) (*models.Poll, error) {
+	flags := append(append([]string{}, tmpl.Flags...), extraFlags...)
+
+	poll := &models.Poll{Options: append([]string{}, tmpl.Options...)}
+	if _, err := ParsePollFlags(poll, strings.Join(flags, " ")); err != nil {
+		return nil, err
+	}
+	poll.Question = tmpl.Question
+
+	return p.Create(ctx, actor, poll)
+}
+
+// channelTeamID returns the team of the channel, it is empty for direct
+// and group messages.
+func channelTeamID(ctx context.Context, client *model.Client4, channelID string) (string, error) {
+	channel, _, err := client.GetChannel(ctx, channelID, "")
+	if err != nil {
+		return "", err
+	}
+	return channel.TeamId, nil
+}
@@ -0,0 +1,114 @@
+package tarantool
+
+import (
+	"fmt"
+	"github.com/tarantool/go-tarantool/v2"
+	"votty/internal/models"
+)
+
+func (s *Storage) CreateSchedule(sc *models.Schedule) error {
+	_, err := s.Conn.Do(
+		tarantool.NewInsertRequest("schedules").Tuple([]interface{}{
+			sc.ID,
+			sc.OwnerID,
+			sc.ChannelID,
+			sc.TemplateScopeID,
+			sc.TemplateName,
+			sc.Cron,
+			sc.Timezone,
+			sc.CloseAfter,
+			sc.NextRun,
+			sc.Paused,
+		}),
+	).Get()
+	return err
+}
+
+func (s *Storage) GetSchedule(id string) (*models.Schedule, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("schedules").
+			Limit(1).
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{id}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, ErrNotFound
+	}
+	return toSchedule(data[0].([]interface{})), nil
+}
+
+func (s *Storage) ListSchedules(ownerID string) ([]*models.Schedule, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("schedules").
+			Index("owner").
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{ownerID}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+	return toSchedules(data), nil
+}
+
+// DueSchedules returns the active schedules whose next run is not later
+// than now.
+func (s *Storage) DueSchedules(now int64) ([]*models.Schedule, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("schedules").
+			Index("next_run").
+			Iterator(tarantool.IterLe).
+			Key([]interface{}{false, now}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+	return toSchedules(data), nil
+}
+
+// SetScheduleNextRun stores the next run time and the paused state.
+func (s *Storage) SetScheduleNextRun(id string, nextRun int64, paused bool) error {
+	_, err := s.Conn.Do(
+		tarantool.NewUpdateRequest("schedules").
+			Key([]interface{}{id}).
+			Operations(tarantool.NewOperations().Assign(8, nextRun).Assign(9, paused)),
+	).Get()
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteSchedule(id string) error {
+	_, err := s.Conn.Do(
+		tarantool.NewDeleteRequest("schedules").
+			Key([]interface{}{id}),
+	).Get()
+	return err
+}
+
+func toSchedules(data []interface{}) []*models.Schedule {
+	schedules := make([]*models.Schedule, 0, len(data))
+	for _, record := range data {
+		schedules = append(schedules, toSchedule(record.([]interface{})))
+	}
+	return schedules
+}
+
+func toSchedule(tuple []interface{}) *models.Schedule {
+	return &models.Schedule{
+		ID:              tuple[0].(string),
+		OwnerID:         tuple[1].(string),
+		ChannelID:       tuple[2].(string),
+		TemplateScopeID: tuple[3].(string),
+		TemplateName:    tuple[4].(string),
+		Cron:            tuple[5].(string),
+		Timezone:        tuple[6].(string),
+		CloseAfter:      toInt64(tuple[7]),
+		NextRun:         toInt64(tuple[8]),
+		Paused:          tuple[9].(bool),
+	}
+}
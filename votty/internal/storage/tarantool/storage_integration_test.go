@@ -0,0 +1,740 @@
+//go:build integration
+
+package tarantool
+
+import (
+	"errors"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/tarantool/go-tarantool/v2"
+	"golang.org/x/exp/slog"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+	"votty/internal/config"
+	"votty/internal/models"
+)
+
+// testStorage connects to the Tarantool running tarantool/init.lua at
+// TARANTOOL_HOST, the tests are skipped without it.
+func testStorage(t *testing.T) *Storage {
+	t.Helper()
+	host := os.Getenv("TARANTOOL_HOST")
+	if host == "" {
+		t.Skip("TARANTOOL_HOST is not set")
+	}
+	user := os.Getenv("TARANTOOL_USER")
+	if user == "" {
+		user = "guest"
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := New(log, &config.Config{TarantoolHost: host, TarantoolUser: user, TarantoolPassword: os.Getenv("TARANTOOL_PASSWORD")})
+	if s == nil {
+		t.Fatalf("failed to connect to Tarantool at %s", host)
+	}
+	t.Cleanup(func() { s.Conn.Close() })
+	return s
+}
+
+// testPoll stores an active poll with the options, it is deleted with its
+// votes when the test ends.
+func testPoll(t *testing.T, s *Storage, options ...string) *models.Poll {
+	t.Helper()
+	return createPoll(t, s, &models.Poll{Options: options})
+}
+
+// createPoll stores the poll as an active one with a new ID, it is deleted
+// with its votes when the test ends.
+func createPoll(t *testing.T, s *Storage, poll *models.Poll) *models.Poll {
+	t.Helper()
+	poll.ID = gonanoid.Must(10)
+	poll.OwnerID = "owner"
+	poll.Question = "Where?"
+	poll.IsActive = true
+	if err := s.CreatePoll(poll); err != nil {
+		t.Fatalf("CreatePoll: %v", err)
+	}
+	t.Cleanup(func() { s.DeletePoll(poll.ID) })
+	return poll
+}
+
+func assertOptions(t *testing.T, s *Storage, pollID string, want ...string) {
+	t.Helper()
+	poll, err := s.GetPoll(pollID)
+	if err != nil {
+		t.Fatalf("GetPoll: %v", err)
+	}
+	if !reflect.DeepEqual(poll.Options, want) {
+		t.Fatalf("options = %q, want %q", poll.Options, want)
+	}
+}
+
+func assertResults(t *testing.T, s *Storage, pollID string, want ...int) {
+	t.Helper()
+	results, err := s.PollResults(pollID, len(want))
+	if err != nil {
+		t.Fatalf("PollResults: %v", err)
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("results = %v, want %v", results, want)
+	}
+}
+
+// userChoice returns the 0-based choice of the user in the poll.
+func userChoice(t *testing.T, s *Storage, pollID, userID string) uint64 {
+	t.Helper()
+	votes, err := s.VotesByUser(userID)
+	if err != nil {
+		t.Fatalf("VotesByUser: %v", err)
+	}
+	for _, vote := range votes {
+		if vote.PollID == pollID {
+			return vote.Choice
+		}
+	}
+	t.Fatalf("user %s has not voted in the poll", userID)
+	return 0
+}
+
+func TestAddOption(t *testing.T) {
+	s := testStorage(t)
+	poll := testPoll(t, s, "a", "Да  нет")
+
+	for _, option := range []string{" A ", "да нет", "ДА\tНЕТ"} {
+		if err := s.AddOption(poll.ID, option, 3); !errors.Is(err, ErrDuplicate) {
+			t.Fatalf("AddOption(%q) = %v, want ErrDuplicate", option, err)
+		}
+	}
+	if err := s.AddOption(poll.ID, "c", 3); err != nil {
+		t.Fatalf("AddOption: %v", err)
+	}
+	assertOptions(t, s, poll.ID, "a", "Да  нет", "c")
+
+	if err := s.AddOption(poll.ID, "d", 3); !errors.Is(err, ErrTooManyOptions) {
+		t.Fatalf("AddOption over the limit = %v, want ErrTooManyOptions", err)
+	}
+	if err := s.AddOption("missing", "d", 3); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("AddOption of a missing poll = %v, want ErrNotFound", err)
+	}
+
+	if _, err := s.EndPoll(poll.ID); err != nil {
+		t.Fatalf("EndPoll: %v", err)
+	}
+	if err := s.AddOption(poll.ID, "d", 10); !errors.Is(err, ErrPollClosed) {
+		t.Fatalf("AddOption of a closed poll = %v, want ErrPollClosed", err)
+	}
+}
+
+func TestRenameOption(t *testing.T) {
+	s := testStorage(t)
+	poll := testPoll(t, s, "a", "b")
+	if _, err := s.CastVote(poll.ID, "u1", 1); err != nil {
+		t.Fatalf("CastVote: %v", err)
+	}
+
+	if err := s.RenameOption(poll.ID, 1, "B"); err != nil {
+		t.Fatalf("RenameOption: %v", err)
+	}
+	assertOptions(t, s, poll.ID, "a", "B")
+	assertResults(t, s, poll.ID, 0, 1)
+
+	if err := s.RenameOption(poll.ID, 1, " A"); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("RenameOption to another option = %v, want ErrDuplicate", err)
+	}
+	if err := s.RenameOption(poll.ID, 2, "c"); !errors.Is(err, ErrInvalidChoice) {
+		t.Fatalf("RenameOption out of range = %v, want ErrInvalidChoice", err)
+	}
+}
+
+func TestRemoveOption(t *testing.T) {
+	s := testStorage(t)
+	poll := testPoll(t, s, "a", "b", "c")
+	for user, choice := range map[string]uint64{"u1": 0, "u2": 1, "u3": 2, "u4": 2} {
+		if _, err := s.CastVote(poll.ID, user, choice); err != nil {
+			t.Fatalf("CastVote: %v", err)
+		}
+	}
+
+	if _, err := s.RemoveOption(poll.ID, 1, false); !errors.Is(err, ErrHasVotes) {
+		t.Fatalf("RemoveOption with votes = %v, want ErrHasVotes", err)
+	}
+	assertOptions(t, s, poll.ID, "a", "b", "c")
+
+	cleared, err := s.RemoveOption(poll.ID, 1, true)
+	if err != nil {
+		t.Fatalf("RemoveOption: %v", err)
+	}
+	if !reflect.DeepEqual(cleared, []string{"u2"}) {
+		t.Fatalf("cleared = %q, want [u2]", cleared)
+	}
+	assertOptions(t, s, poll.ID, "a", "c")
+	assertResults(t, s, poll.ID, 1, 2)
+
+	if choice := userChoice(t, s, poll.ID, "u3"); choice != 1 {
+		t.Fatalf("shifted choice = %v, want 1", choice)
+	}
+	if fixed, err := s.RebuildTallies(poll.ID); err != nil || len(fixed) > 0 {
+		t.Fatalf("RebuildTallies = %v, %v, want the tallies to match the votes", fixed, err)
+	}
+
+	if _, err = s.RemoveOption(poll.ID, 5, true); !errors.Is(err, ErrInvalidChoice) {
+		t.Fatalf("RemoveOption out of range = %v, want ErrInvalidChoice", err)
+	}
+	if _, err = s.RemoveOption(poll.ID, 0, true); err != nil {
+		t.Fatalf("RemoveOption: %v", err)
+	}
+	if _, err = s.RemoveOption(poll.ID, 0, true); !errors.Is(err, ErrLastOption) {
+		t.Fatalf("RemoveOption of the last option = %v, want ErrLastOption", err)
+	}
+}
+
+func TestSaveTemplateWithoutFlags(t *testing.T) {
+	s := testStorage(t)
+	template := &models.Template{
+		ScopeID:  "user-" + gonanoid.Must(6),
+		Name:     "Lunch",
+		Scope:    models.TemplateScopeUser,
+		OwnerID:  "owner",
+		Question: "Where?",
+		Options:  []string{"a", "b"},
+	}
+	if err := s.SaveTemplate(template); err != nil {
+		t.Fatalf("SaveTemplate: %v", err)
+	}
+	t.Cleanup(func() { s.DeleteTemplate(template.ScopeID, template.Name) })
+
+	stored, err := s.GetTemplate(template.ScopeID, template.Name)
+	if err != nil {
+		t.Fatalf("GetTemplate: %v", err)
+	}
+	if len(stored.Flags) != 0 || !reflect.DeepEqual(stored.Options, template.Options) {
+		t.Fatalf("stored template = %+v, want %+v", stored, template)
+	}
+}
+
+func TestEndPollOnce(t *testing.T) {
+	s := testStorage(t)
+	poll := testPoll(t, s, "a", "b")
+
+	var wg sync.WaitGroup
+	results := make(chan bool, 10)
+	for i := 0; i < cap(results); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			closed, err := s.EndPoll(poll.ID)
+			if err != nil {
+				t.Errorf("EndPoll: %v", err)
+			}
+			results <- closed
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	closes := 0
+	for closed := range results {
+		if closed {
+			closes++
+		}
+	}
+	if closes != 1 {
+		t.Fatalf("the poll has been closed %v times, want once", closes)
+	}
+	if _, err := s.EndPoll("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("EndPoll of a missing poll = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCastVote(t *testing.T) {
+	s := testStorage(t)
+	poll := testPoll(t, s, "a", "b", "c")
+	locked := createPoll(t, s, &models.Poll{Options: []string{"a", "b"}, LockedVotes: true})
+	closed := testPoll(t, s, "a", "b")
+	if _, err := s.EndPoll(closed.ID); err != nil {
+		t.Fatalf("EndPoll: %v", err)
+	}
+	trashed := testPoll(t, s, "a", "b")
+	if err := s.SoftDeletePoll(trashed.ID, 1); err != nil {
+		t.Fatalf("SoftDeletePoll: %v", err)
+	}
+
+	// the steps run in order and share the votes
+	steps := []struct {
+		name    string
+		pollID  string
+		userID  string
+		choice  uint64
+		changed bool
+		wantErr error
+	}{
+		{name: "first vote", pollID: poll.ID, userID: "u1", choice: 0},
+		{name: "another user", pollID: poll.ID, userID: "u2", choice: 0},
+		{name: "changed vote", pollID: poll.ID, userID: "u1", choice: 2, changed: true},
+		{name: "same vote again", pollID: poll.ID, userID: "u2", choice: 0, changed: true},
+		{name: "invalid option", pollID: poll.ID, userID: "u3", choice: 3, wantErr: ErrInvalidChoice},
+		{name: "missing poll", pollID: "missing", userID: "u1", choice: 0, wantErr: ErrNotFound},
+		{name: "trashed poll", pollID: trashed.ID, userID: "u1", choice: 0, wantErr: ErrNotFound},
+		{name: "closed poll", pollID: closed.ID, userID: "u1", choice: 0, wantErr: ErrPollClosed},
+		{name: "locked first vote", pollID: locked.ID, userID: "u1", choice: 1},
+		{name: "locked change", pollID: locked.ID, userID: "u1", choice: 0, wantErr: ErrAlreadyExists},
+	}
+
+	for _, step := range steps {
+		changed, err := s.CastVote(step.pollID, step.userID, step.choice)
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: err = %v, want %v", step.name, err, step.wantErr)
+		}
+		if changed != step.changed {
+			t.Fatalf("%s: changed = %v, want %v", step.name, changed, step.changed)
+		}
+	}
+
+	assertResults(t, s, poll.ID, 1, 0, 1)
+	assertResults(t, s, locked.ID, 0, 1)
+	if choice := userChoice(t, s, poll.ID, "u1"); choice != 2 {
+		t.Fatalf("choice of u1 = %d, want 2", choice)
+	}
+}
+
+func TestRetractVote(t *testing.T) {
+	s := testStorage(t)
+	poll := testPoll(t, s, "a", "b")
+	locked := createPoll(t, s, &models.Poll{Options: []string{"a", "b"}, LockedVotes: true})
+	for _, pollID := range []string{poll.ID, locked.ID} {
+		for _, userID := range []string{"u1", "u2"} {
+			if _, err := s.CastVote(pollID, userID, 1); err != nil {
+				t.Fatalf("CastVote: %v", err)
+			}
+		}
+	}
+
+	steps := []struct {
+		name    string
+		pollID  string
+		userID  string
+		wantErr error
+	}{
+		{name: "retracted", pollID: poll.ID, userID: "u1"},
+		{name: "retracted twice", pollID: poll.ID, userID: "u1", wantErr: ErrNoVote},
+		{name: "never voted", pollID: poll.ID, userID: "u3", wantErr: ErrNoVote},
+		{name: "locked", pollID: locked.ID, userID: "u1", wantErr: ErrAlreadyExists},
+		{name: "missing poll", pollID: "missing", userID: "u1", wantErr: ErrNotFound},
+	}
+
+	for _, step := range steps {
+		if err := s.RetractVote(step.pollID, step.userID); !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: err = %v, want %v", step.name, err, step.wantErr)
+		}
+	}
+	assertResults(t, s, poll.ID, 0, 1)
+	assertResults(t, s, locked.ID, 0, 2)
+
+	if _, err := s.EndPoll(poll.ID); err != nil {
+		t.Fatalf("EndPoll: %v", err)
+	}
+	if err := s.RetractVote(poll.ID, "u2"); !errors.Is(err, ErrPollClosed) {
+		t.Fatalf("RetractVote of a closed poll = %v, want ErrPollClosed", err)
+	}
+	assertResults(t, s, poll.ID, 0, 1)
+}
+
+func TestRebuildTallies(t *testing.T) {
+	s := testStorage(t)
+	poll := testPoll(t, s, "a", "b", "c")
+	for userID, choice := range map[string]uint64{"u1": 0, "u2": 0, "u3": 1} {
+		if _, err := s.CastVote(poll.ID, userID, choice); err != nil {
+			t.Fatalf("CastVote: %v", err)
+		}
+	}
+
+	fixed, err := s.RebuildTallies(poll.ID)
+	if err != nil {
+		t.Fatalf("RebuildTallies: %v", err)
+	}
+	if len(fixed) != 0 {
+		t.Fatalf("RebuildTallies of consistent tallies fixed %q", fixed)
+	}
+
+	// a wrong count, a missing tally and a tally of an option without votes
+	for _, tally := range [][]interface{}{{poll.ID, 0, 5}, {poll.ID, 2, 1}} {
+		if _, err = s.Conn.Do(tarantool.NewReplaceRequest("poll_tallies").Tuple(tally)).Get(); err != nil {
+			t.Fatalf("replace tally: %v", err)
+		}
+	}
+	if _, err = s.Conn.Do(tarantool.NewDeleteRequest("poll_tallies").Key([]interface{}{poll.ID, 1})).Get(); err != nil {
+		t.Fatalf("delete tally: %v", err)
+	}
+	assertResults(t, s, poll.ID, 5, 0, 1)
+
+	fixed, err = s.RebuildTallies(poll.ID)
+	if err != nil {
+		t.Fatalf("RebuildTallies: %v", err)
+	}
+	if !reflect.DeepEqual(fixed, []string{poll.ID}) {
+		t.Fatalf("RebuildTallies fixed %q, want %q", fixed, []string{poll.ID})
+	}
+	assertResults(t, s, poll.ID, 2, 1, 0)
+
+	// without a poll ID all polls are checked
+	if _, err = s.Conn.Do(tarantool.NewReplaceRequest("poll_tallies").Tuple([]interface{}{poll.ID, 1, 7})).Get(); err != nil {
+		t.Fatalf("replace tally: %v", err)
+	}
+	if fixed, err = s.RebuildTallies(""); err != nil {
+		t.Fatalf("RebuildTallies: %v", err)
+	}
+	if !slices.Contains(fixed, poll.ID) {
+		t.Fatalf("RebuildTallies of all polls fixed %q, want %s among them", fixed, poll.ID)
+	}
+	assertResults(t, s, poll.ID, 2, 1, 0)
+}
+
+func TestEraseUser(t *testing.T) {
+	s := testStorage(t)
+	first := testPoll(t, s, "a", "b")
+	second := testPoll(t, s, "a", "b")
+	userID := gonanoid.Must(10)
+	for _, vote := range []struct {
+		pollID, userID string
+		choice         uint64
+	}{{first.ID, userID, 0}, {second.ID, userID, 1}, {first.ID, "u2", 1}} {
+		if _, err := s.CastVote(vote.pollID, vote.userID, vote.choice); err != nil {
+			t.Fatalf("CastVote: %v", err)
+		}
+	}
+	suggestion := &models.Suggestion{ID: gonanoid.Must(10), PollID: first.ID, UserID: userID, Option: "c", CreatedAt: 1}
+	if err := s.CreateSuggestion(suggestion); err != nil {
+		t.Fatalf("CreateSuggestion: %v", err)
+	}
+	t.Cleanup(func() { s.DeleteSuggestion(suggestion.ID) })
+	if err := s.SetReminderOptOut(userID, true); err != nil {
+		t.Fatalf("SetReminderOptOut: %v", err)
+	}
+	event := &models.Event{ID: gonanoid.Must(), Type: models.EventVoteCast, CreatedAt: 1}
+	if err := s.EnqueueEvent(event, []byte(`{"vote":{"user_id":"`+userID+`"},"other":"`+userID+`x"}`)); err != nil {
+		t.Fatalf("EnqueueEvent: %v", err)
+	}
+	t.Cleanup(func() { s.DeleteEvent(event.ID) })
+
+	erased, err := s.EraseUser(userID, true)
+	if err != nil {
+		t.Fatalf("EraseUser(anonymize): %v", err)
+	}
+	if want := (Erased{Votes: 2, Suggestions: 1, Events: 1}); *erased != want {
+		t.Fatalf("EraseUser(anonymize) = %+v, want %+v", *erased, want)
+	}
+	assertResults(t, s, first.ID, 1, 1)
+	assertResults(t, s, second.ID, 0, 1)
+	if votes, _ := s.VotesByUser(userID); len(votes) != 0 {
+		t.Fatalf("the user still has %d votes", len(votes))
+	}
+	if suggestions, _ := s.UserSuggestions(userID); len(suggestions) != 0 {
+		t.Fatalf("the user still has %d suggestions", len(suggestions))
+	}
+	if optedOut, _ := s.IsReminderOptedOut(userID); optedOut {
+		t.Fatal("the user is still opted out of the reminders")
+	}
+	events, err := s.DueEvents(1, 1000)
+	if err != nil {
+		t.Fatalf("DueEvents: %v", err)
+	}
+	i := slices.IndexFunc(events, func(e *QueuedEvent) bool { return e.ID == event.ID })
+	if i < 0 {
+		t.Fatal("the queued event has been lost")
+	}
+	if want := `{"vote":{"user_id":"erased"},"other":"` + userID + `x"}`; string(events[i].Payload) != want {
+		t.Fatalf("queued payload = %s, want %s", events[i].Payload, want)
+	}
+
+	if _, err = s.CastVote(first.ID, userID, 0); err != nil {
+		t.Fatalf("CastVote: %v", err)
+	}
+	if erased, err = s.EraseUser(userID, false); err != nil || erased.Votes != 1 {
+		t.Fatalf("EraseUser = %+v, %v, want 1 vote", erased, err)
+	}
+	assertResults(t, s, first.ID, 1, 1)
+	if fixed, _ := s.RebuildTallies(first.ID); len(fixed) != 0 {
+		t.Fatal("erasing the votes left the tallies inconsistent")
+	}
+}
+
+func TestPollNumbers(t *testing.T) {
+	s := testStorage(t)
+	channelID := gonanoid.Must(10)
+
+	var polls []*models.Poll
+	for i := 0; i < 3; i++ {
+		number, err := s.NextPollNumber(channelID)
+		if err != nil {
+			t.Fatalf("NextPollNumber: %v", err)
+		}
+		if number != uint64(i+1) {
+			t.Fatalf("number = %d, want %d", number, i+1)
+		}
+		polls = append(polls, createPoll(t, s, &models.Poll{Options: []string{"a"}, ChannelID: channelID, Number: number}))
+	}
+
+	poll, err := s.PollByNumber(channelID, 2)
+	if err != nil || poll.ID != polls[1].ID {
+		t.Fatalf("PollByNumber(2) = %v, %v, want %s", poll, err, polls[1].ID)
+	}
+	if _, err = s.PollByNumber(channelID, 4); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("PollByNumber(4) = %v, want ErrNotFound", err)
+	}
+	if _, err = s.PollByNumber(gonanoid.Must(10), 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("PollByNumber of another channel = %v, want ErrNotFound", err)
+	}
+
+	if poll, err = s.LastPoll(channelID); err != nil || poll.ID != polls[2].ID {
+		t.Fatalf("LastPoll = %v, %v, want %s", poll, err, polls[2].ID)
+	}
+	if err = s.SoftDeletePoll(polls[2].ID, 1); err != nil {
+		t.Fatalf("SoftDeletePoll: %v", err)
+	}
+	if poll, err = s.LastPoll(channelID); err != nil || poll.ID != polls[1].ID {
+		t.Fatalf("LastPoll skipping the trash = %v, %v, want %s", poll, err, polls[1].ID)
+	}
+
+	// deleted polls never give their numbers back
+	if err = s.DeletePoll(polls[1].ID); err != nil {
+		t.Fatalf("DeletePoll: %v", err)
+	}
+	if number, _ := s.NextPollNumber(channelID); number != 4 {
+		t.Fatalf("number after a delete = %d, want 4", number)
+	}
+	if _, err = s.LastPoll(gonanoid.Must(10)); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("LastPoll of an empty channel = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPollByPost(t *testing.T) {
+	s := testStorage(t)
+	postID := gonanoid.Must(10)
+	channelID := gonanoid.Must(10)
+	first := createPoll(t, s, &models.Poll{Options: []string{"a"}, ChannelID: channelID, Number: 1, PostID: postID})
+	second := createPoll(t, s, &models.Poll{Options: []string{"a"}, ChannelID: channelID, Number: 2})
+	if err := s.SetPollPost(second.ID, postID); err != nil {
+		t.Fatalf("SetPollPost: %v", err)
+	}
+
+	poll, err := s.PollByPost(postID)
+	if err != nil || poll.ID != second.ID {
+		t.Fatalf("PollByPost = %v, %v, want the latest poll %s", poll, err, second.ID)
+	}
+	if err = s.SoftDeletePoll(second.ID, 1); err != nil {
+		t.Fatalf("SoftDeletePoll: %v", err)
+	}
+	if poll, err = s.PollByPost(postID); err != nil || poll.ID != first.ID {
+		t.Fatalf("PollByPost skipping the trash = %v, %v, want %s", poll, err, first.ID)
+	}
+	if _, err = s.PollByPost("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("PollByPost of a missing post = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeletedPolls(t *testing.T) {
+	s := testStorage(t)
+	// the times are far in the past, so no other trashed poll is due before them
+	var ids []string
+	for _, deletedAt := range []int64{100, 200, 300} {
+		poll := testPoll(t, s, "a")
+		if err := s.SoftDeletePoll(poll.ID, deletedAt); err != nil {
+			t.Fatalf("SoftDeletePoll: %v", err)
+		}
+		ids = append(ids, poll.ID)
+	}
+	testPoll(t, s, "a")
+
+	tests := []struct {
+		before int64
+		limit  uint32
+		want   []string
+	}{
+		{before: 250, limit: 10, want: []string{ids[1], ids[0]}},
+		{before: 300, limit: 2, want: []string{ids[2], ids[1]}},
+		{before: 99, limit: 10, want: nil},
+	}
+
+	for _, tt := range tests {
+		polls, err := s.DeletedPolls(tt.before, tt.limit)
+		if err != nil {
+			t.Fatalf("DeletedPolls: %v", err)
+		}
+		var got []string
+		for _, poll := range polls {
+			got = append(got, poll.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DeletedPolls(%d, %d) = %q, want %q", tt.before, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestClosedPolls(t *testing.T) {
+	s := testStorage(t)
+	// the times are far in the past, so no other closed poll is due before them
+	var ids []string
+	for _, closedAt := range []int64{100, 200, 300} {
+		poll := testPoll(t, s, "a")
+		request := tarantool.NewUpdateRequest("polls").
+			Key([]interface{}{poll.ID}).
+			Operations(tarantool.NewOperations().Assign(4, false).Assign(17, closedAt))
+		if _, err := s.Conn.Do(request).Get(); err != nil {
+			t.Fatalf("close poll: %v", err)
+		}
+		ids = append(ids, poll.ID)
+	}
+	testPoll(t, s, "a")
+	if _, err := s.AnonymizeVotes(ids[1]); err != nil {
+		t.Fatalf("AnonymizeVotes: %v", err)
+	}
+
+	pollIDs := func(polls []*models.Poll, err error) []string {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("select closed polls: %v", err)
+		}
+		var got []string
+		for _, poll := range polls {
+			got = append(got, poll.ID)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{name: "closed", got: pollIDs(s.ClosedPolls(250, 10)), want: []string{ids[1], ids[0]}},
+		{name: "closed with limit", got: pollIDs(s.ClosedPolls(300, 2)), want: []string{ids[2], ids[1]}},
+		{name: "closed before all", got: pollIDs(s.ClosedPolls(99, 10))},
+		{name: "to anonymize", got: pollIDs(s.PollsToAnonymize(300, 10)), want: []string{ids[2], ids[0]}},
+		{name: "to anonymize with limit", got: pollIDs(s.PollsToAnonymize(300, 1)), want: []string{ids[2]}},
+		{name: "to anonymize before all", got: pollIDs(s.PollsToAnonymize(99, 10))},
+	}
+
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestReminderOptedOut(t *testing.T) {
+	s := testStorage(t)
+	first, second := gonanoid.Must(10), gonanoid.Must(10)
+	for _, userID := range []string{first, second} {
+		if err := s.SetReminderOptOut(userID, true); err != nil {
+			t.Fatalf("SetReminderOptOut: %v", err)
+		}
+		t.Cleanup(func() { s.SetReminderOptOut(userID, false) })
+	}
+
+	tests := []struct {
+		name    string
+		userIDs []string
+		want    map[string]bool
+	}{
+		{name: "none", userIDs: []string{}, want: map[string]bool{}},
+		{name: "nobody opted out", userIDs: []string{gonanoid.Must(10)}, want: map[string]bool{}},
+		{name: "some", userIDs: []string{first, gonanoid.Must(10), second}, want: map[string]bool{first: true, second: true}},
+	}
+
+	for _, tt := range tests {
+		got, err := s.ReminderOptedOut(tt.userIDs)
+		if err != nil {
+			t.Fatalf("%s: ReminderOptedOut: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ReminderOptedOut = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClaimReminder(t *testing.T) {
+	s := testStorage(t)
+	poll := testPoll(t, s, "Here", "There")
+
+	if err := s.ClaimReminder(poll.ID, time.Hour); err != nil {
+		t.Fatalf("first ClaimReminder: %v", err)
+	}
+	if err := s.ClaimReminder(poll.ID, time.Hour); !errors.Is(err, ErrTooSoon) {
+		t.Errorf("repeated ClaimReminder err = %v, want ErrTooSoon", err)
+	}
+	if err := s.ClaimReminder(poll.ID, 0); err != nil {
+		t.Errorf("ClaimReminder without cooldown: %v", err)
+	}
+	if err := s.ClaimReminder(gonanoid.Must(10), time.Hour); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ClaimReminder of a missing poll err = %v, want ErrNotFound", err)
+	}
+
+	got, err := s.GetPoll(poll.ID)
+	if err != nil {
+		t.Fatalf("GetPoll: %v", err)
+	}
+	if got.RemindedAt == 0 {
+		t.Errorf("RemindedAt is not set")
+	}
+}
+
+func TestApproveSuggestion(t *testing.T) {
+	s := testStorage(t)
+	poll := testPoll(t, s, "a", "b")
+	suggest := func(option string) string {
+		t.Helper()
+		suggestion := &models.Suggestion{ID: gonanoid.Must(10), PollID: poll.ID, UserID: "u1", Option: option, CreatedAt: 1}
+		if err := s.CreateSuggestion(suggestion); err != nil {
+			t.Fatalf("CreateSuggestion: %v", err)
+		}
+		t.Cleanup(func() { s.DeleteSuggestion(suggestion.ID) })
+		return suggestion.ID
+	}
+
+	first, second := suggest("c"), suggest("C ")
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i, id := range []string{first, first, second} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.ApproveSuggestion(id, 10)
+		}()
+	}
+	wg.Wait()
+
+	added := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			added++
+		case !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrDuplicate):
+			t.Errorf("ApproveSuggestion = %v, want nil, ErrNotFound or ErrDuplicate", err)
+		}
+	}
+	if added != 1 {
+		t.Errorf("%d approvals added the option, want 1", added)
+	}
+	got, err := s.GetPoll(poll.ID)
+	if err != nil {
+		t.Fatalf("GetPoll: %v", err)
+	}
+	if len(got.Options) != 3 {
+		t.Errorf("options = %q, want one more option", got.Options)
+	}
+
+	if err := s.ApproveSuggestion(first, 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("repeated ApproveSuggestion = %v, want ErrNotFound", err)
+	}
+	full := suggest("d")
+	if err := s.ApproveSuggestion(full, 3); !errors.Is(err, ErrTooManyOptions) {
+		t.Errorf("ApproveSuggestion over the limit = %v, want ErrTooManyOptions", err)
+	}
+	if _, err := s.GetSuggestion(full); err != nil {
+		t.Errorf("rejected approval deleted the suggestion: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package tarantool
+
+import (
+	"github.com/tarantool/go-tarantool/v2"
+	"votty/internal/models"
+)
+
+func (s *Storage) CreateSuggestion(suggestion *models.Suggestion) error {
+	_, err := s.Conn.Do(
+		tarantool.NewInsertRequest("suggestions").Tuple([]interface{}{
+			suggestion.ID,
+			suggestion.PollID,
+			suggestion.UserID,
+			suggestion.Option,
+			suggestion.CreatedAt,
+		}),
+	).Get()
+	return err
+}
+
+func (s *Storage) GetSuggestion(id string) (*models.Suggestion, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("suggestions").
+			Limit(1).
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{id}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, ErrNotFound
+	}
+	return toSuggestion(data[0].([]interface{})), nil
+}
+
+// PollSuggestions returns the suggestions of the poll waiting for approval.
+func (s *Storage) PollSuggestions(pollID string) ([]*models.Suggestion, error) {
+	return s.selectSuggestions("poll", pollID)
+}
+
+// UserSuggestions returns the suggestions of the user waiting for approval.
+func (s *Storage) UserSuggestions(userID string) ([]*models.Suggestion, error) {
+	return s.selectSuggestions("user", userID)
+}
+
+func (s *Storage) selectSuggestions(index, key string) ([]*models.Suggestion, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("suggestions").
+			Index(index).
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{key}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]*models.Suggestion, 0, len(data))
+	for _, record := range data {
+		suggestions = append(suggestions, toSuggestion(record.([]interface{})))
+	}
+	return suggestions, nil
+}
+
+// ApproveSuggestion adds the suggested option to its poll, which must have
+// fewer than max options, and deletes the suggestion in one transaction of
+// the approve_suggestion schema function. It returns ErrNotFound if the
+// suggestion has already been decided on, the other errors are the ones of
+// AddOption and keep the suggestion.
+func (s *Storage) ApproveSuggestion(id string, max int) error {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("approve_suggestion").
+			Args([]interface{}{id, max}),
+	).Get()
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 && data[0] == "no_suggestion" {
+		return ErrNotFound
+	}
+	return editStatus("approve_suggestion", "added", data)
+}
+
+func (s *Storage) DeleteSuggestion(id string) error {
+	data, err := s.Conn.Do(
+		tarantool.NewDeleteRequest("suggestions").
+			Key([]interface{}{id}),
+	).Get()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func toSuggestion(tuple []interface{}) *models.Suggestion {
+	return &models.Suggestion{
+		ID:        tuple[0].(string),
+		PollID:    tuple[1].(string),
+		UserID:    tuple[2].(string),
+		Option:    tuple[3].(string),
+		CreatedAt: toInt64(tuple[4]),
+	}
+}
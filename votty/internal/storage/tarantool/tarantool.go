@@ -12,7 +12,16 @@ import (
 )
 
 var (
-	ErrNotFound = errors.New("data not found")
+	ErrNotFound       = errors.New("data not found")
+	ErrAlreadyExists  = errors.New("data already exists")
+	ErrPollClosed     = errors.New("poll is closed")
+	ErrInvalidChoice  = errors.New("choice is out of range")
+	ErrNoVote         = errors.New("user has not voted")
+	ErrHasVotes       = errors.New("option has votes")
+	ErrLastOption     = errors.New("option is the last one")
+	ErrTooManyOptions = errors.New("poll has too many options")
+	ErrDuplicate      = errors.New("option already exists")
+	ErrTooSoon        = errors.New("action was repeated too soon")
 )
 
 type Storage struct {
@@ -45,8 +54,33 @@ func New(log *slog.Logger, cfg *config.Config) *Storage {
 	return &Storage{Conn: conn}
 }
 
-func (s *Storage) CreatePoll(id, owner, question string, options []string) error {
-	request := tarantool.NewInsertRequest("polls").Tuple([]interface{}{id, owner, question, options, true})
+func (s *Storage) CreatePoll(poll *models.Poll) error {
+	request := tarantool.NewInsertRequest("polls").Tuple([]interface{}{
+		poll.ID,
+		poll.OwnerID,
+		poll.Question,
+		poll.Options,
+		poll.IsActive,
+		poll.LockedVotes,
+		poll.ResultsVisibility,
+		poll.ChannelID,
+		poll.Quorum,
+		poll.Threshold,
+		poll.VotersMode,
+		poll.Voters,
+		poll.ClosesAt,
+		poll.RemindBefore,
+		poll.Reminded,
+		poll.Anonymous,
+		poll.DeletedAt,
+		poll.ClosedAt,
+		poll.VotesAnonymized,
+		poll.Number,
+		poll.PostID,
+		poll.Reactions,
+		poll.OpenOptions,
+		poll.RemindedAt,
+	})
 
 	future := s.Conn.Do(request)
 
@@ -71,29 +105,311 @@ func (s *Storage) GetPoll(id string) (*models.Poll, error) {
 	}
 
 	if len(data) > 0 {
-		tuple := data[0].([]interface{})
-		poll := models.Poll{
-			ID:       tuple[0].(string),
-			OwnerID:  tuple[1].(string),
-			Question: tuple[2].(string),
-			Options:  toStringSlice(tuple[3].([]interface{})),
-			IsActive: tuple[4].(bool),
-		}
-		return &poll, nil
+		return toPoll(data[0].([]interface{})), nil
 	} else {
 		return nil, ErrNotFound
 	}
 }
 
+// DeletePoll deletes the poll with its votes, tallies and suggestions in
+// one transaction of the delete_poll schema function.
 func (s *Storage) DeletePoll(id string) error {
-	_, err := s.Conn.Do(
-		tarantool.NewDeleteRequest("polls").
-			Key([]interface{}{id}),
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("delete_poll").
+			Args([]interface{}{id}),
+	).Get()
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		return fmt.Errorf("delete_poll returned nothing")
+	}
+	if deleted, _ := data[0].(bool); !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// NextPollNumber reserves the next channel-local number of a poll.
+func (s *Storage) NextPollNumber(channelID string) (uint64, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("next_poll_number").
+			Args([]interface{}{channelID}),
+	).Get()
+	if err != nil {
+		return 0, err
+	}
+	if len(data) == 0 {
+		return 0, fmt.Errorf("next_poll_number returned nothing")
+	}
+	return toUint64(data[0]), nil
+}
+
+// PollByNumber returns the poll with the channel-local number.
+func (s *Storage) PollByNumber(channelID string, number uint64) (*models.Poll, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("polls").
+			Index("number").
+			Limit(1).
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{channelID, number}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, ErrNotFound
+	}
+	return toPoll(data[0].([]interface{})), nil
+}
+
+// LastPoll returns the latest numbered poll of the channel that is not in
+// the trash.
+func (s *Storage) LastPoll(channelID string) (*models.Poll, error) {
+	const page = 20
+
+	for offset := uint32(0); ; offset += page {
+		data, err := s.Conn.Do(
+			tarantool.NewSelectRequest("polls").
+				Index("number").
+				Offset(offset).
+				Limit(page).
+				Iterator(tarantool.IterReq).
+				Key([]interface{}{channelID}),
+		).Get()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, poll := range toPolls(data) {
+			if poll.Number > 0 && poll.DeletedAt == 0 {
+				return poll, nil
+			}
+		}
+		if len(data) < page {
+			return nil, ErrNotFound
+		}
+	}
+}
+
+// PollByPost returns the poll announced by the post or in its thread.
+// When a thread holds several polls, the latest one is returned.
+func (s *Storage) PollByPost(postID string) (*models.Poll, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("polls").
+			Index("post").
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{postID}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *models.Poll
+	for _, poll := range toPolls(data) {
+		if poll.DeletedAt == 0 && (latest == nil || poll.Number > latest.Number) {
+			latest = poll
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+// SetPollPost records the post that announced the poll.
+func (s *Storage) SetPollPost(pollID, postID string) error {
+	request := tarantool.NewUpdateRequest("polls").
+		Key([]interface{}{pollID}).
+		Operations(tarantool.NewOperations().Assign(20, postID))
+
+	_, err := s.Conn.Do(request).Get()
+	return err
+}
+
+// SoftDeletePoll moves the poll to the trash, it is kept with its votes
+// until it is restored or purged.
+func (s *Storage) SoftDeletePoll(id string, deletedAt int64) error {
+	request := tarantool.NewUpdateRequest("polls").
+		Key([]interface{}{id}).
+		Operations(tarantool.NewOperations().Assign(16, deletedAt))
+
+	_, err := s.Conn.Do(request).Get()
+	return err
+}
+
+// RestorePoll takes the poll out of the trash.
+func (s *Storage) RestorePoll(id string) error {
+	return s.SoftDeletePoll(id, 0)
+}
+
+// DeletedPolls returns up to limit polls moved to the trash at or before
+// the time, the latest first.
+func (s *Storage) DeletedPolls(before int64, limit uint32) ([]*models.Poll, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("polls").
+			Index("deleted").
+			Limit(limit).
+			Iterator(tarantool.IterLe).
+			Key([]interface{}{before}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var polls []*models.Poll
+	for _, poll := range toPolls(data) {
+		// the polls outside the trash come last
+		if poll.DeletedAt == 0 {
+			break
+		}
+		polls = append(polls, poll)
+	}
+	return polls, nil
+}
+
+// ClosedPolls returns up to limit polls closed at or before the time, the
+// latest first.
+func (s *Storage) ClosedPolls(before int64, limit uint32) ([]*models.Poll, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("polls").
+			Index("closed").
+			Limit(limit).
+			Iterator(tarantool.IterLe).
+			Key([]interface{}{before}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+	return closedPolls(data), nil
+}
+
+// PollsToAnonymize returns up to limit polls closed at or before the time
+// whose votes have not been anonymized yet, the latest first.
+func (s *Storage) PollsToAnonymize(before int64, limit uint32) ([]*models.Poll, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("polls").
+			Index("anonymized").
+			Limit(limit).
+			Iterator(tarantool.IterLe).
+			Key([]interface{}{false, before}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+	return closedPolls(data), nil
+}
+
+// closedPolls maps the tuples of a descending scan by the closing time,
+// which ends with the active polls.
+func closedPolls(data []interface{}) []*models.Poll {
+	var polls []*models.Poll
+	for _, poll := range toPolls(data) {
+		if poll.ClosedAt == 0 {
+			break
+		}
+		polls = append(polls, poll)
+	}
+	return polls
+}
+
+// AnonymizeVotes replaces the user IDs in the votes of the poll with random
+// ones and returns the number of anonymized votes.
+func (s *Storage) AnonymizeVotes(pollID string) (int, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("anonymize_votes").
+			Args([]interface{}{pollID}),
+	).Get()
+	if err != nil {
+		return 0, err
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	return int(toUint64(data[0])), nil
+}
+
+// VotesByUser returns all votes of the user.
+func (s *Storage) VotesByUser(userID string) ([]*models.Vote, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("votes").
+			Index("user").
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{userID}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	votes := make([]*models.Vote, 0, len(data))
+	for _, record := range data {
+		tuple := record.([]interface{})
+		votes = append(votes, &models.Vote{
+			PollID: tuple[0].(string),
+			UserID: tuple[1].(string),
+			Choice: toUint64(tuple[2]),
+		})
+	}
+	return votes, nil
+}
+
+// Erased counts what EraseUser has removed.
+type Erased struct {
+	Votes       int
+	Suggestions int
+	// Events are the queued webhook events the user ID was redacted from.
+	Events int
+}
+
+// EraseUser anonymizes or deletes all votes of the user, keeping the
+// tallies consistent, deletes the user's suggestions and reminder opt-out
+// and redacts the user ID from the queued webhook events, all in one
+// transaction of the erase_user schema function.
+func (s *Storage) EraseUser(userID string, anonymize bool) (*Erased, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("erase_user").
+			Args([]interface{}{userID, anonymize}),
 	).Get()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 3 {
+		return nil, fmt.Errorf("erase_user returned %d values", len(data))
+	}
+	return &Erased{
+		Votes:       int(toUint64(data[0])),
+		Suggestions: int(toUint64(data[1])),
+		Events:      int(toUint64(data[2])),
+	}, nil
+}
+
+// ReassignPoll makes the user the owner of the poll.
+func (s *Storage) ReassignPoll(pollID, ownerID string) error {
+	request := tarantool.NewUpdateRequest("polls").
+		Key([]interface{}{pollID}).
+		Operations(tarantool.NewOperations().Assign(1, ownerID))
 
+	_, err := s.Conn.Do(request).Get()
 	return err
 }
 
+// PurgeOrphans deletes the votes and tallies of polls that no longer exist
+// and returns the number of purged votes.
+func (s *Storage) PurgeOrphans() (int, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("purge_orphans"),
+	).Get()
+	if err != nil {
+		return 0, err
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	return int(toUint64(data[0])), nil
+}
+
 func (s *Storage) SelectVotes(pollID, userID string) (*models.Vote, error) {
 	data, err := s.Conn.Do(
 		tarantool.NewSelectRequest("votes").
@@ -118,23 +434,44 @@ func (s *Storage) SelectVotes(pollID, userID string) (*models.Vote, error) {
 	return nil, ErrNotFound
 }
 
-func (s *Storage) UpsertVote(pollID, userID string, choice uint64) error {
-	_, err := s.Conn.Do(
-		tarantool.NewUpsertRequest("votes").
-			Tuple([]interface{}{pollID, userID, choice}).
-			Operations(tarantool.NewOperations().Assign(2, choice)),
+// CastVote stores the vote with the cast_vote function of the schema, which
+// checks the poll and writes the vote in one transaction. It reports
+// whether an earlier vote of the user has been replaced.
+func (s *Storage) CastVote(pollID, userID string, choice uint64) (bool, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("cast_vote").
+			Args([]interface{}{pollID, userID, choice}),
 	).Get()
 	if err != nil {
-		return err
+		return false, err
+	}
+	if len(data) == 0 {
+		return false, fmt.Errorf("cast_vote returned nothing")
 	}
-	return nil
 
+	switch status, _ := data[0].(string); status {
+	case "new":
+		return false, nil
+	case "changed":
+		return true, nil
+	case "not_found":
+		return false, ErrNotFound
+	case "closed":
+		return false, ErrPollClosed
+	case "invalid_option":
+		return false, ErrInvalidChoice
+	case "locked":
+		return false, ErrAlreadyExists
+	default:
+		return false, fmt.Errorf("unexpected cast_vote status %v", data[0])
+	}
 }
 
+// PollResults returns the vote counts of the poll from its tallies, which
+// the schema functions keep up to date with the votes.
 func (s *Storage) PollResults(pollID string, optionsSize int) ([]int, error) {
-
 	data, err := s.Conn.Do(
-		tarantool.NewSelectRequest("votes").
+		tarantool.NewSelectRequest("poll_tallies").
 			Iterator(tarantool.IterEq).
 			Key([]interface{}{pollID}),
 	).Get()
@@ -146,25 +483,418 @@ func (s *Storage) PollResults(pollID string, optionsSize int) ([]int, error) {
 	voteCounts := make([]int, optionsSize)
 	for _, record := range data {
 		tuple := record.([]interface{})
-		choice := tuple[2].(uint64)
+		option := toUint64(tuple[1])
 
-		voteCounts[int(choice)]++
+		if option < uint64(optionsSize) {
+			voteCounts[option] = int(toUint64(tuple[2]))
+		}
 	}
 	return voteCounts, nil
 }
 
-func (s *Storage) EndPoll(pollID string) error {
-	request := tarantool.NewUpdateRequest("polls").
-		Key([]interface{}{pollID}).
-		Operations(tarantool.NewOperations().Assign(4, false))
+// RebuildTallies recomputes the tallies of the poll, or of all polls if
+// pollID is empty, from the raw votes. It returns the polls whose tallies
+// had to be fixed.
+func (s *Storage) RebuildTallies(pollID string) ([]string, error) {
+	var arg interface{}
+	if pollID != "" {
+		arg = pollID
+	}
+
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("rebuild_tallies").
+			Args([]interface{}{arg}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	fixed, _ := data[0].([]interface{})
+	return toStringSlice(fixed), nil
+}
+
+// EndPoll closes the poll with the end_poll function of the schema. It
+// reports whether this call has closed the poll, false means the poll had
+// already been closed, possibly by a concurrent call.
+func (s *Storage) EndPoll(pollID string) (bool, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("end_poll").
+			Args([]interface{}{pollID}),
+	).Get()
+	if err != nil {
+		return false, fmt.Errorf("failed to end poll: %w", err)
+	}
+	if len(data) == 0 {
+		return false, fmt.Errorf("end_poll returned nothing")
+	}
+
+	switch status, _ := data[0].(string); status {
+	case "closed":
+		return true, nil
+	case "already_closed":
+		return false, nil
+	case "not_found":
+		return false, ErrNotFound
+	default:
+		return false, fmt.Errorf("unexpected end_poll status %v", data[0])
+	}
+}
+
+// RetractVote removes the vote with the retract_vote function of the
+// schema, which checks the poll, deletes the vote and updates the tallies
+// in one transaction.
+func (s *Storage) RetractVote(pollID, userID string) error {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("retract_vote").
+			Args([]interface{}{pollID, userID}),
+	).Get()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("retract_vote returned nothing")
+	}
+
+	switch status, _ := data[0].(string); status {
+	case "retracted":
+		return nil
+	case "not_found":
+		return ErrNotFound
+	case "closed":
+		return ErrPollClosed
+	case "locked":
+		return ErrAlreadyExists
+	case "no_vote":
+		return ErrNoVote
+	default:
+		return fmt.Errorf("unexpected retract_vote status %v", data[0])
+	}
+}
+
+// UpdateQuestion changes the question of the poll.
+func (s *Storage) UpdateQuestion(pollID, question string) error {
+	data, err := s.Conn.Do(
+		tarantool.NewUpdateRequest("polls").
+			Key([]interface{}{pollID}).
+			Operations(tarantool.NewOperations().Assign(2, question)),
+	).Get()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddOption appends the option to an active poll that has fewer than max
+// options with the add_option schema function. It returns ErrDuplicate if
+// the poll already has the option.
+func (s *Storage) AddOption(pollID, option string, max int) error {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("add_option").
+			Args([]interface{}{pollID, option, max}),
+	).Get()
+	if err != nil {
+		return err
+	}
+	return editStatus("add_option", "added", data)
+}
+
+// RenameOption changes the 0-based option of an active poll, its votes are
+// kept.
+func (s *Storage) RenameOption(pollID string, option uint64, text string) error {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("rename_option").
+			Args([]interface{}{pollID, option, text}),
+	).Get()
+	if err != nil {
+		return err
+	}
+	return editStatus("rename_option", "renamed", data)
+}
+
+// RemoveOption removes the 0-based option of an active poll in one
+// transaction with its votes and tallies. An option with votes is removed
+// only with clear, which returns the users whose votes were deleted.
+func (s *Storage) RemoveOption(pollID string, option uint64, clear bool) ([]string, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("remove_option").
+			Args([]interface{}{pollID, option, clear}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+	if err = editStatus("remove_option", "removed", data); err != nil {
+		return nil, err
+	}
+
+	var cleared []string
+	if len(data) > 1 {
+		if users, ok := data[1].([]interface{}); ok {
+			cleared = toStringSlice(users)
+		}
+	}
+	return cleared, nil
+}
+
+// editStatus maps the status returned by the option editing functions of
+// the schema to the errors.
+func editStatus(function, success string, data []interface{}) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%s returned nothing", function)
+	}
+
+	switch status, _ := data[0].(string); status {
+	case success:
+		return nil
+	case "not_found":
+		return ErrNotFound
+	case "closed":
+		return ErrPollClosed
+	case "invalid_option":
+		return ErrInvalidChoice
+	case "has_votes":
+		return ErrHasVotes
+	case "last_option":
+		return ErrLastOption
+	case "too_many":
+		return ErrTooManyOptions
+	case "duplicate":
+		return ErrDuplicate
+	default:
+		return fmt.Errorf("unexpected %s status %v", function, data[0])
+	}
+}
+
+func (s *Storage) ListPollsByOwner(ownerID string) ([]*models.Poll, error) {
+	return s.selectPolls("owner", ownerID)
+}
+
+func (s *Storage) ListPollsByChannel(channelID string) ([]*models.Poll, error) {
+	return s.selectPolls("channel", channelID)
+}
+
+func (s *Storage) selectPolls(index, key string) ([]*models.Poll, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("polls").
+			Index(index).
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{key}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return toPolls(data), nil
+}
+
+// PollVoters returns the IDs of all users who voted in the poll.
+func (s *Storage) PollVoters(pollID string) ([]string, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("votes").
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{pollID}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	voters := make([]string, 0, len(data))
+	for _, record := range data {
+		tuple := record.([]interface{})
+		voters = append(voters, tuple[1].(string))
+	}
+	return voters, nil
+}
+
+// PollsWithDeadline returns the active polls that have a deadline,
+// ordered by it.
+func (s *Storage) PollsWithDeadline() ([]*models.Poll, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("polls").
+			Index("deadline").
+			Iterator(tarantool.IterGt).
+			Key([]interface{}{true, 0}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return toPolls(data), nil
+}
+
+func (s *Storage) MarkReminded(pollID string) error {
+	_, err := s.Conn.Do(
+		tarantool.NewUpdateRequest("polls").
+			Key([]interface{}{pollID}).
+			Operations(tarantool.NewOperations().Assign(14, true)),
+	).Get()
+	if err != nil {
+		return fmt.Errorf("failed to mark poll as reminded: %w", err)
+	}
+	return nil
+}
+
+// SetReminderOptOut turns the reminder DMs off or back on for the user.
+func (s *Storage) SetReminderOptOut(userID string, optOut bool) error {
+	var request tarantool.Request = tarantool.NewDeleteRequest("reminder_optouts").
+		Key([]interface{}{userID})
+	if optOut {
+		request = tarantool.NewReplaceRequest("reminder_optouts").
+			Tuple([]interface{}{userID})
+	}
 
 	_, err := s.Conn.Do(request).Get()
+	return err
+}
+
+func (s *Storage) IsReminderOptedOut(userID string) (bool, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("reminder_optouts").
+			Limit(1).
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{userID}),
+	).Get()
 	if err != nil {
-		return fmt.Errorf("failed to end poll: %w", err)
+		return false, err
 	}
+	return len(data) > 0, nil
+}
+
+// ClaimReminder records the reminders requested by the owner of the poll
+// with the claim_reminder function of the schema. It returns ErrTooSoon
+// if the previous reminders were sent less than cooldown ago.
+func (s *Storage) ClaimReminder(pollID string, cooldown time.Duration) error {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("claim_reminder").
+			Args([]interface{}{pollID, int64(cooldown.Seconds())}),
+	).Get()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("claim_reminder returned nothing")
+	}
+
+	switch status, _ := data[0].(string); status {
+	case "claimed":
+		return nil
+	case "too_soon":
+		return ErrTooSoon
+	case "not_found":
+		return ErrNotFound
+	default:
+		return fmt.Errorf("unexpected claim_reminder status %v", data[0])
+	}
+}
+
+// ReminderOptedOut returns which of the users have turned the reminders
+// off.
+func (s *Storage) ReminderOptedOut(userIDs []string) (map[string]bool, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewCallRequest("reminder_opted_out").
+			Args([]interface{}{userIDs}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	optedOut := make(map[string]bool)
+	if len(data) > 0 {
+		ids, _ := data[0].([]interface{})
+		for _, id := range toStringSlice(ids) {
+			optedOut[id] = true
+		}
+	}
+	return optedOut, nil
+}
+
+func toPolls(data []interface{}) []*models.Poll {
+	polls := make([]*models.Poll, 0, len(data))
+	for _, record := range data {
+		polls = append(polls, toPoll(record.([]interface{})))
+	}
+	return polls
+}
+
+// toPoll maps a polls tuple to the model. Fields added to the space after
+// its creation are nullable, so older tuples may be shorter than the format.
+func toPoll(tuple []interface{}) *models.Poll {
+	poll := &models.Poll{
+		ID:       tuple[0].(string),
+		OwnerID:  tuple[1].(string),
+		Question: tuple[2].(string),
+		Options:  toStringSlice(tuple[3].([]interface{})),
+		IsActive: tuple[4].(bool),
+	}
+	poll.LockedVotes, _ = field(tuple, 5).(bool)
+	poll.ResultsVisibility, _ = field(tuple, 6).(string)
+	poll.ChannelID, _ = field(tuple, 7).(string)
+	poll.Quorum = toUint64(field(tuple, 8))
+	poll.Threshold = toUint64(field(tuple, 9))
+	poll.VotersMode, _ = field(tuple, 10).(string)
+	if voters, ok := field(tuple, 11).([]interface{}); ok {
+		poll.Voters = toStringSlice(voters)
+	}
+	poll.ClosesAt = toInt64(field(tuple, 12))
+	poll.RemindBefore = toInt64(field(tuple, 13))
+	poll.Reminded, _ = field(tuple, 14).(bool)
+	poll.Anonymous, _ = field(tuple, 15).(bool)
+	poll.DeletedAt = toInt64(field(tuple, 16))
+	poll.ClosedAt = toInt64(field(tuple, 17))
+	poll.VotesAnonymized, _ = field(tuple, 18).(bool)
+	poll.Number = toUint64(field(tuple, 19))
+	poll.PostID, _ = field(tuple, 20).(string)
+	poll.Reactions, _ = field(tuple, 21).(string)
+	poll.OpenOptions, _ = field(tuple, 22).(string)
+	poll.RemindedAt = toInt64(field(tuple, 23))
 
+	return poll
+}
+
+// field returns the i-th element of the tuple or nil if the tuple is shorter.
+func field(tuple []interface{}, i int) interface{} {
+	if i < len(tuple) {
+		return tuple[i]
+	}
 	return nil
 }
+
+// toUint64 converts an unsigned field of any msgpack width, nil becomes 0.
+func toUint64(v interface{}) uint64 {
+	switch n := v.(type) {
+	case uint64:
+		return n
+	case uint32:
+		return uint64(n)
+	case uint16:
+		return uint64(n)
+	case uint8:
+		return uint64(n)
+	case int64:
+		return uint64(n)
+	case int32:
+		return uint64(n)
+	case int16:
+		return uint64(n)
+	case int8:
+		return uint64(n)
+	}
+	return 0
+}
+
+// toInt64 converts an integer field of any msgpack width, nil becomes 0.
+func toInt64(v interface{}) int64 {
+	if n, ok := v.(int64); ok {
+		return n
+	}
+	return int64(toUint64(v))
+}
+
 func toStringSlice(data []interface{}) []string {
 	result := make([]string, len(data))
 	for i, v := range data {
@@ -0,0 +1,97 @@
+package tarantool
+
+import (
+	"reflect"
+	"testing"
+	"votty/internal/models"
+)
+
+func TestToUint64(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want uint64
+	}{
+		{name: "nil", v: nil, want: 0},
+		{name: "uint8", v: uint8(7), want: 7},
+		{name: "uint16", v: uint16(300), want: 300},
+		{name: "uint32", v: uint32(70000), want: 70000},
+		{name: "uint64", v: uint64(1 << 40), want: 1 << 40},
+		{name: "int8", v: int8(5), want: 5},
+		{name: "int64", v: int64(1700000000), want: 1700000000},
+		{name: "string", v: "3", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toUint64(tt.v); got != tt.want {
+				t.Errorf("toUint64(%#v) = %d, want %d", tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToInt64(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want int64
+	}{
+		{name: "nil", v: nil, want: 0},
+		{name: "negative", v: int64(-5), want: -5},
+		{name: "negative int8", v: int8(-5), want: -5},
+		{name: "uint32", v: uint32(1700000000), want: 1700000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toInt64(tt.v); got != tt.want {
+				t.Errorf("toInt64(%#v) = %d, want %d", tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToPoll(t *testing.T) {
+	tests := []struct {
+		name  string
+		tuple []interface{}
+		want  *models.Poll
+	}{
+		{
+			name:  "tuple of the first version",
+			tuple: []interface{}{"poll", "owner", "Where?", []interface{}{"Here", "There"}, true},
+			want:  &models.Poll{ID: "poll", OwnerID: "owner", Question: "Where?", Options: []string{"Here", "There"}, IsActive: true},
+		},
+		{
+			name: "nulls of the nullable fields",
+			tuple: []interface{}{"poll", "owner", "Where?", []interface{}{"Here"}, false,
+				nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil},
+			want: &models.Poll{ID: "poll", OwnerID: "owner", Question: "Where?", Options: []string{"Here"}},
+		},
+		{
+			name: "all fields",
+			tuple: []interface{}{"poll", "owner", "Where?", []interface{}{"+1", "-1"}, false,
+				true, models.ResultsAfterClose, "channel", uint8(3), uint16(2), models.VotersUsers, []interface{}{"u1"},
+				int64(1700000000), uint32(3600), true, true, uint64(1700000100), uint32(1700000200), true,
+				uint8(7), "post", models.ReactionsSingle, models.OptionsApproval, uint32(1700000300)},
+			want: &models.Poll{
+				ID: "poll", OwnerID: "owner", Question: "Where?", Options: []string{"+1", "-1"},
+				LockedVotes: true, ResultsVisibility: models.ResultsAfterClose, ChannelID: "channel",
+				Quorum: 3, Threshold: 2, VotersMode: models.VotersUsers, Voters: []string{"u1"},
+				ClosesAt: 1700000000, RemindBefore: 3600, Reminded: true, Anonymous: true,
+				DeletedAt: 1700000100, ClosedAt: 1700000200, VotesAnonymized: true,
+				Number: 7, PostID: "post", Reactions: models.ReactionsSingle, OpenOptions: models.OptionsApproval,
+				RemindedAt: 1700000300,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toPoll(tt.tuple); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("toPoll() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package tarantool
+
+import (
+	"github.com/tarantool/go-tarantool/v2"
+	"votty/internal/models"
+)
+
+// SaveTemplate creates the template or replaces the one with the same name
+// in its scope.
+func (s *Storage) SaveTemplate(t *models.Template) error {
+	flags := t.Flags
+	if flags == nil {
+		// the flags field is a non-nullable array and nil is encoded as nil
+		flags = []string{}
+	}
+	_, err := s.Conn.Do(
+		tarantool.NewReplaceRequest("templates").Tuple([]interface{}{
+			t.ScopeID,
+			t.Name,
+			t.Scope,
+			t.OwnerID,
+			t.Question,
+			t.Options,
+			flags,
+		}),
+	).Get()
+	return err
+}
+
+func (s *Storage) GetTemplate(scopeID, name string) (*models.Template, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("templates").
+			Limit(1).
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{scopeID, name}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, ErrNotFound
+	}
+	return toTemplate(data[0].([]interface{})), nil
+}
+
+// ListTemplates returns all templates of a user or a team.
+func (s *Storage) ListTemplates(scopeID string) ([]*models.Template, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("templates").
+			Iterator(tarantool.IterEq).
+			Key([]interface{}{scopeID}),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]*models.Template, 0, len(data))
+	for _, record := range data {
+		templates = append(templates, toTemplate(record.([]interface{})))
+	}
+	return templates, nil
+}
+
+func (s *Storage) DeleteTemplate(scopeID, name string) error {
+	_, err := s.Conn.Do(
+		tarantool.NewDeleteRequest("templates").
+			Key([]interface{}{scopeID, name}),
+	).Get()
+	return err
+}
+
+func toTemplate(tuple []interface{}) *models.Template {
+	t := &models.Template{
+		ScopeID:  tuple[0].(string),
+		Name:     tuple[1].(string),
+		Scope:    tuple[2].(string),
+		OwnerID:  tuple[3].(string),
+		Question: tuple[4].(string),
+		Options:  toStringSlice(tuple[5].([]interface{})),
+	}
+	if flags, ok := field(tuple, 6).([]interface{}); ok {
+		t.Flags = toStringSlice(flags)
+	}
+	return t
+}
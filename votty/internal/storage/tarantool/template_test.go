@@ -0,0 +1,30 @@
+package tarantool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToTemplate(t *testing.T) {
+	tests := []struct {
+		name  string
+		tuple []interface{}
+		want  []string
+	}{
+		{name: "without flags field", tuple: []interface{}{"u1", "daily", "user", "u1", "Where?", []interface{}{"Here"}}},
+		{name: "empty flags", tuple: []interface{}{"u1", "daily", "user", "u1", "Where?", []interface{}{"Here"}, []interface{}{}}, want: []string{}},
+		{name: "flags", tuple: []interface{}{"u1", "daily", "user", "u1", "Where?", []interface{}{"Here"}, []interface{}{"--locked"}}, want: []string{"--locked"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toTemplate(tt.tuple)
+			if got.Question != "Where?" || !reflect.DeepEqual(got.Options, []string{"Here"}) {
+				t.Errorf("toTemplate() = %+v", got)
+			}
+			if !reflect.DeepEqual(got.Flags, tt.want) {
+				t.Errorf("Flags = %#v, want %#v", got.Flags, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package tarantool
+
+import (
+	"github.com/tarantool/go-tarantool/v2"
+	"votty/internal/models"
+)
+
+// QueuedEvent is an outbound webhook event waiting for delivery.
+type QueuedEvent struct {
+	ID      string
+	Type    string
+	Payload []byte
+	// PendingURLs are the webhooks that have not accepted the event yet,
+	// nil means all configured ones.
+	PendingURLs []string
+	Attempts    uint64
+	NextAttempt int64
+}
+
+// EnqueueEvent stores the event in the persistent webhook queue.
+func (s *Storage) EnqueueEvent(event *models.Event, payload []byte) error {
+	_, err := s.Conn.Do(
+		tarantool.NewInsertRequest("webhook_queue").Tuple([]interface{}{
+			event.ID,
+			event.Type,
+			string(payload),
+			nil,
+			uint64(0),
+			event.CreatedAt,
+		}),
+	).Get()
+	return err
+}
+
+// DueEvents returns up to limit queued events whose next attempt is not
+// later than now, the oldest first.
+func (s *Storage) DueEvents(now int64, limit uint32) ([]*QueuedEvent, error) {
+	data, err := s.Conn.Do(
+		tarantool.NewSelectRequest("webhook_queue").
+			Index("next_attempt").
+			Iterator(tarantool.IterAll).
+			Limit(limit),
+	).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*QueuedEvent, 0, len(data))
+	for _, record := range data {
+		tuple := record.([]interface{})
+		event := &QueuedEvent{
+			ID:          tuple[0].(string),
+			Type:        tuple[1].(string),
+			Payload:     []byte(tuple[2].(string)),
+			Attempts:    toUint64(tuple[4]),
+			NextAttempt: toInt64(tuple[5]),
+		}
+		if event.NextAttempt > now {
+			break
+		}
+		if urls, ok := tuple[3].([]interface{}); ok {
+			event.PendingURLs = toStringSlice(urls)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// RescheduleEvent keeps the event in the queue for the webhooks that
+// have not accepted it yet.
+func (s *Storage) RescheduleEvent(id string, pendingURLs []string, attempts uint64, nextAttempt int64) error {
+	_, err := s.Conn.Do(
+		tarantool.NewUpdateRequest("webhook_queue").
+			Key([]interface{}{id}).
+			Operations(tarantool.NewOperations().
+				Assign(3, pendingURLs).
+				Assign(4, attempts).
+				Assign(5, nextAttempt)),
+	).Get()
+	return err
+}
+
+func (s *Storage) DeleteEvent(id string) error {
+	_, err := s.Conn.Do(
+		tarantool.NewDeleteRequest("webhook_queue").
+			Key([]interface{}{id}),
+	).Get()
+	return err
+}
@@ -0,0 +1,163 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
+	"fmt"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"votty/internal/config"
+	"votty/internal/storage/tarantool"
+)
+
+const (
+	pollInterval = 5 * time.Second
+	batchSize    = 50
+	maxAttempts  = 10
+	baseBackoff  = 10 * time.Second
+	maxBackoff   = time.Hour
+)
+
+// metrics are published at /debug/vars of the bot HTTP server.
+var metrics = expvar.NewMap("webhooks")
+
+// Sender delivers the events from the persistent queue to the configured
+// webhooks. Every request carries the time of sending in the
+// X-Votty-Timestamp header and the HMAC-SHA256 of the timestamp and the
+// body in the X-Votty-Signature header, so a captured delivery cannot be
+// replayed later.
+type Sender struct {
+	log    *slog.Logger
+	urls   []string
+	secret []byte
+	client *http.Client
+}
+
+func New(log *slog.Logger, cfg *config.Config) *Sender {
+	return &Sender{
+		log:    log,
+		urls:   cfg.WebhookURLs,
+		secret: []byte(cfg.WebhookSecret),
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Run delivers the queued events until the context is canceled.
+func (s *Sender) Run(ctx context.Context, storage *tarantool.Storage) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.deliverDue(ctx, storage)
+		}
+	}
+}
+
+func (s *Sender) deliverDue(ctx context.Context, storage *tarantool.Storage) {
+	now := time.Now()
+
+	events, err := storage.DueEvents(now.Unix(), batchSize)
+	if err != nil {
+		s.log.Error("Failed to select queued webhook events",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	for _, event := range events {
+		pending := event.PendingURLs
+		if pending == nil {
+			pending = s.urls
+		}
+
+		var failed []string
+		for _, url := range pending {
+			if err := s.send(ctx, url, event); err != nil {
+				metrics.Add("failed", 1)
+				s.log.Warn("Failed to deliver the webhook",
+					slog.String("url", url),
+					slog.String("event_id", event.ID),
+					slog.String("event", event.Type),
+					slog.Uint64("attempt", event.Attempts+1),
+					slog.String("error", err.Error()),
+				)
+				failed = append(failed, url)
+				continue
+			}
+			metrics.Add("delivered", 1)
+		}
+
+		if len(failed) == 0 || event.Attempts+1 >= maxAttempts {
+			if len(failed) > 0 {
+				metrics.Add("dropped", int64(len(failed)))
+				s.log.Error("Giving up on the webhook event",
+					slog.String("event_id", event.ID),
+					slog.String("event", event.Type),
+					slog.Any("urls", failed),
+				)
+			}
+			err = storage.DeleteEvent(event.ID)
+		} else {
+			next := now.Add(backoff(event.Attempts + 1))
+			err = storage.RescheduleEvent(event.ID, failed, event.Attempts+1, next.Unix())
+		}
+		if err != nil {
+			s.log.Error("Failed to update the webhook queue",
+				slog.String("event_id", event.ID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+func (s *Sender) send(ctx context.Context, url string, event *tarantool.QueuedEvent) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(event.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Votty-Event", event.Type)
+	req.Header.Set("X-Votty-Delivery", event.ID)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("X-Votty-Timestamp", timestamp)
+	req.Header.Set("X-Votty-Signature", "sha256="+Sign(s.secret, timestamp, event.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the timestamp, a dot and the
+// payload.
+func Sign(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff doubles the delay after every failed attempt up to maxBackoff.
+func backoff(attempts uint64) time.Duration {
+	d := baseBackoff
+	for i := uint64(1); i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
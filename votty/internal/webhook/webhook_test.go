@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"context"
+	"golang.org/x/exp/slog"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"votty/internal/config"
+	"votty/internal/storage/tarantool"
+)
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"type":"poll.closed"}`)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		payload   []byte
+		want      string
+	}{
+		{
+			name:      "payload",
+			secret:    "secret",
+			timestamp: "1700000000",
+			payload:   payload,
+			want:      "222ce63b28ab13c3f17d82d12d68583c2d8c32e6099db3f8413041761d839393",
+		},
+		{
+			name:      "empty payload",
+			secret:    "secret",
+			timestamp: "1700000000",
+			want:      "4bc5f74d868b97888288889c5d9d65df02526f94c1592a79fdf4fe8b26e311e5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign([]byte(tt.secret), tt.timestamp, tt.payload); got != tt.want {
+				t.Errorf("Sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	signature := Sign([]byte("secret"), "1700000000", payload)
+	if Sign([]byte("secret"), "1700000001", payload) == signature {
+		t.Error("the signature does not depend on the timestamp")
+	}
+	if Sign([]byte("other"), "1700000000", payload) == signature {
+		t.Error("the signature does not depend on the secret")
+	}
+	// the dot keeps the timestamp and the payload apart
+	if Sign([]byte("secret"), "17", []byte(`00000000.{"type":"poll.closed"}`)) == signature {
+		t.Error("the timestamp can be shifted into the payload")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts uint64
+		want     time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{6, 320 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{64, time.Hour},
+		{1 << 40, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestSendSigned(t *testing.T) {
+	var (
+		header http.Header
+		body   []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), &config.Config{WebhookSecret: "secret"})
+	event := &tarantool.QueuedEvent{ID: "delivery", Type: "poll.closed", Payload: []byte(`{"type":"poll.closed"}`)}
+
+	before := time.Now().Unix()
+	if err := s.send(context.Background(), server.URL, event); err != nil {
+		t.Fatalf("send() = %v", err)
+	}
+
+	if got := header.Get("X-Votty-Event"); got != event.Type {
+		t.Errorf("X-Votty-Event = %q, want %q", got, event.Type)
+	}
+	if got := header.Get("X-Votty-Delivery"); got != event.ID {
+		t.Errorf("X-Votty-Delivery = %q, want %q", got, event.ID)
+	}
+
+	timestamp := header.Get("X-Votty-Timestamp")
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || sent < before || sent > time.Now().Unix() {
+		t.Errorf("X-Votty-Timestamp = %q, want the time of sending", timestamp)
+	}
+	if want := "sha256=" + Sign([]byte("secret"), timestamp, body); header.Get("X-Votty-Signature") != want {
+		t.Errorf("X-Votty-Signature = %q, want %q", header.Get("X-Votty-Signature"), want)
+	}
+}
+
+func TestSendStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	s := New(slog.New(slog.NewTextHandler(io.Discard, nil)), &config.Config{})
+	if err := s.send(context.Background(), server.URL, &tarantool.QueuedEvent{}); err == nil {
+		t.Error("send() accepted a failed delivery")
+	}
+}